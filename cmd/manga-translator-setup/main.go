@@ -1,19 +1,53 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/cameronkinsella/manga-translator/pkg/config"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os"
 	"path/filepath"
 )
 
 func main() {
+	// Parse flags. If any config fields or a JSON config are given, the config is written without prompts.
+	credentialsPtr := flag.String("vision-credentials", "", "Path to the service account key for the Vision API.")
+	servicePtr := flag.String("service", "", `Translation service: "google" or "deepL". Defaults to "deepL" if there is a DeepL key, otherwise "google".`)
+	googleKeyPtr := flag.String("google-key", "", "Google Cloud Translation API key.")
+	deepLKeyPtr := flag.String("deepl-key", "", "DeepL API key.")
+	sourcePtr := flag.String("source", "", "Source language code. Automatically detected if omitted.")
+	targetPtr := flag.String("target", "", "Target language code.")
+	jsonPtr := flag.String("from-json", "", `Read the config as JSON from the given file, or from stdin if "-". Other flags override its fields.`)
+	offlinePtr := flag.Bool("offline", false, "Skip checking the languages against the service's supported languages.")
+	configDirPtr := flag.String("config", "", "Directory used for the config, cache and history files. Overrides MTL_HOME.")
+	flag.Parse()
+	if *configDirPtr != "" {
+		config.SetHome(*configDirPtr)
+	}
+
+	values := map[string]string{
+		"vision-credentials": *credentialsPtr,
+		"service":            *servicePtr,
+		"google-key":         *googleKeyPtr,
+		"deepl-key":          *deepLKeyPtr,
+		"source":             *sourcePtr,
+		"target":             *targetPtr,
+	}
+	// Other flags, like -offline, only change how the config is set up.
+	var fieldFlags int
+	flag.Visit(func(f *flag.Flag) {
+		if _, ok := values[f.Name]; ok || f.Name == "from-json" {
+			fieldFlags++
+		}
+	})
+
 	// Set up logging.
 	log.SetFormatter(&log.JSONFormatter{})
 	log.SetLevel(log.DebugLevel)
 
 	settings := config.Path()
-	logPath := filepath.Join(settings, "mtl-logrus.log")
+	logPath := filepath.Join(config.StatePath(), "mtl-logrus.log")
 	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
 	if err == nil {
 		log.SetOutput(f)
@@ -22,12 +56,96 @@ func main() {
 	}
 	defer f.Close()
 
-	// Try loading config file.
+	// Try loading config file. Environment variable overrides are not applied, so they are never saved to the file.
 	var cfg config.File
-	config.Setup(settings, &cfg)
+	config.Load(settings, &cfg)
+
+	if fieldFlags > 0 {
+		log.Info("Running non-interactive setup")
+		if errs := nonInteractive(&cfg, *jsonPtr, *offlinePtr, values); len(errs) > 0 {
+			fmt.Fprintln(os.Stderr, "Config is invalid, nothing was written:")
+			for _, err := range errs {
+				log.Error(err)
+				fmt.Fprintf(os.Stderr, "  %v\n", err)
+			}
+			os.Exit(1)
+		}
+		if err := config.SaveConfig(cfg); err != nil {
+			log.Error(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Config written to %s\n", filepath.Join(settings, "mtl-config.yml"))
+		return
+	}
 
 	// We only want to start from scratch if there is no existing config, otherwise we modify existing config.
-	modify := cfg != config.File{}
+	modify := !cfg.Empty()
 
 	config.Create(modify)
 }
+
+// nonInteractive updates the given config with the given JSON config and flag values, and validates the result.
+// Only the flags which were set on the command line are applied, so an existing config can be partially updated.
+func nonInteractive(cfg *config.File, jsonPath string, offline bool, values map[string]string) []error {
+	if jsonPath != "" {
+		var r io.Reader = os.Stdin
+		if jsonPath != "-" {
+			jsonFile, err := os.Open(jsonPath)
+			if err != nil {
+				return []error{err}
+			}
+			defer jsonFile.Close()
+			r = jsonFile
+		}
+		if err := config.LoadJSON(r, cfg); err != nil {
+			return []error{err}
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		v := values[f.Name]
+		switch f.Name {
+		case "vision-credentials":
+			cfg.CloudVision.CredentialsPath = v
+		case "service":
+			cfg.Translation.SelectedService = v
+		case "google-key":
+			cfg.Translation.Google.APIKey = v
+		case "deepl-key":
+			cfg.Translation.DeepL.APIKey = v
+		case "source":
+			cfg.Translation.SourceLanguage = v
+		case "target":
+			cfg.Translation.TargetLanguage = v
+		}
+	})
+
+	// Same defaults as the interactive setup, which only asks for the service if there is a DeepL key.
+	if cfg.Translation.SelectedService == "" {
+		if cfg.Translation.DeepL.APIKey != "" {
+			cfg.Translation.SelectedService = "deepL"
+		} else {
+			cfg.Translation.SelectedService = "google"
+		}
+	}
+	if cfg.Translation.TargetLanguage == "" {
+		cfg.Translation.TargetLanguage = config.DefaultTargetLanguage(cfg.Translation.SelectedService)
+	}
+
+	var errs []error
+	for _, err := range config.Validate(*cfg) {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 || offline {
+		return errs
+	}
+
+	if err := config.SetCredentials(*cfg); err != nil {
+		return []error{err}
+	}
+	for _, err := range config.ValidateLanguages(cfg) {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// cacheUsage is the usage of the cache command.
+var cacheUsage = `Usage: %s cache COMMAND [OPTIONS]
+
+Commands:
+  export [-o FILE] [IMAGE_LOCATION...]   Write the cache as JSON lines, only the given images if any are given.
+  import [FILE]                          Merge JSON lines written by "cache export" into the cache.
+  stats                                  Show the number of entries and their size by service and language.
+  prune [-hash HASH,...] [-older-than DURATION] [-max-size SIZE]
+                                         Remove the entries of the given images (and their halves, if they were
+                                         split into two pages), entries added before the given duration (e.g. 720h),
+                                         or the least recently used entries above the given size (e.g. 500MB).
+  verify [-salvage]                      Check that every entry can be read. With -salvage, remove the entries which
+                                         can't, the original file is kept as mtl-cache.bin.corrupt.
+
+Options:
+`
+
+// cacheCommand runs the given cache subcommand. Returns the exit status.
+func cacheCommand(args []string) int {
+	fs := flag.NewFlagSet("cache", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), cacheUsage, os.Args[0])
+		fs.PrintDefaults()
+	}
+	outputPtr := fs.String("o", "", "File the cache is exported to. Defaults to stdout.")
+	urlImagePtr := fs.Bool("url", false, "The images given to export are URLs instead of local files.")
+	hashPtr := fs.String("hash", "", "Comma-separated sha256 hashes of the images whose entries are pruned.")
+	olderThanPtr := fs.Duration("older-than", 0, "Prune the entries added longer ago than this duration, e.g. 720h.")
+	maxSizePtr := fs.String("max-size", "", "Prune the least recently used entries until the cache is at most this size, e.g. 500MB.")
+	salvagePtr := fs.Bool("salvage", false, "Remove the entries which can't be read when verifying.")
+	configDirPtr := fs.String("config", "", "Directory used for the config, cache and history files. Overrides MTL_HOME.")
+	if len(args) == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd := args[0]
+	fs.Parse(args[1:])
+	if *configDirPtr != "" {
+		config.SetHome(*configDirPtr)
+	}
+	f := setupLogging()
+	defer f.Close()
+
+	switch cmd {
+	case "export":
+		return cacheExport(*outputPtr, fs.Args(), *urlImagePtr)
+	case "import":
+		var cfg config.File
+		if err := config.Setup(config.Path(), "", nil, &cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return cacheImport(fs.Arg(0), cfg.Cache.MaxSizeBytes())
+	case "stats":
+		return cacheStats()
+	case "prune":
+		maxSize, err := config.ParseSize(*maxSizePtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		opts := cache.PruneOptions{OlderThan: *olderThanPtr, MaxSize: maxSize}
+		if *hashPtr != "" {
+			for _, h := range strings.Split(*hashPtr, ",") {
+				// Include the halves, like export.
+				opts.Hashes = append(opts.Hashes, h)
+				opts.Hashes = append(opts.Hashes, imageW.HalfHashes(h)...)
+			}
+		}
+		return cachePrune(opts)
+	case "verify":
+		return cacheVerify(*salvagePtr)
+	default:
+		fs.Usage()
+		return 2
+	}
+}
+
+// cacheExport writes the cache entries of the given images, or all entries if none are given, to the given file.
+func cacheExport(output string, paths []string, url bool) int {
+	var hashes []string
+	for _, path := range paths {
+		img, err := imageW.Load(path, url, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 1
+		}
+		// Include the halves, in case the image was split into two pages.
+		hashes = append(hashes, img.Hash)
+		hashes = append(hashes, imageW.HalfHashes(img.Hash)...)
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		out, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer out.Close()
+		w = out
+	}
+	n, err := cache.Export(w, hashes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d entries\n", n)
+	return 0
+}
+
+// cacheImport merges the entries in the given file, or stdin if it is blank or "-", into the cache.
+// The least recently used entries are removed if the cache is larger than maxSize bytes.
+func cacheImport(input string, maxSize int64) int {
+	var r io.Reader = os.Stdin
+	if input != "" && input != "-" {
+		in, err := os.Open(input)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer in.Close()
+		r = in
+	}
+	res, err := cache.Import(r, maxSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Nothing was imported: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Added %d entries, replaced %d, kept %d existing entries which are newer or edited\n", res.Added, res.Replaced, res.Kept)
+	if res.Evicted > 0 {
+		fmt.Fprintf(os.Stderr, "Removed %d least recently used entries to stay within cache.maxSize\n", res.Evicted)
+	}
+	return 0
+}
+
+// cacheStats prints the number of entries in the cache and their size, by service and language.
+func cacheStats() int {
+	stats, err := cache.Inspect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%d entries, %s\n\n", stats.Entries, config.FormatSize(stats.Size))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tLANGUAGE\tENTRIES\tSIZE")
+	for _, g := range stats.Groups {
+		lang := g.Language
+		if lang == "" {
+			lang = "(any)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", g.Service, lang, g.Entries, config.FormatSize(g.Size))
+	}
+	tw.Flush()
+	return 0
+}
+
+// cachePrune removes the entries selected by the given options.
+func cachePrune(opts cache.PruneOptions) int {
+	if len(opts.Hashes) == 0 && opts.OlderThan <= 0 && opts.MaxSize <= 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to prune, give -hash, -older-than or -max-size")
+		return 2
+	}
+	n, err := cache.Prune(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Removed %d entries\n", n)
+	return 0
+}
+
+// cacheVerify checks the cache, and removes the entries which can't be used if salvage is true.
+func cacheVerify(salvage bool) int {
+	verify := cache.Verify
+	if salvage {
+		verify = cache.Salvage
+	}
+	n, err := verify()
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "The cache is OK, %d entries\n", n)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "The cache is corrupted: %v\n", err)
+	if salvage {
+		fmt.Fprintf(os.Stderr, "Kept %d readable entries\n", n)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "%d entries are readable, run with -salvage to keep only those\n", n)
+	return 1
+}
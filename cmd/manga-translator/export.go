@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"github.com/cameronkinsella/manga-translator/pkg/script"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
+	"github.com/cameronkinsella/manga-translator/pkg/window"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+)
+
+// export detects and translates the given images without opening a window, and writes their text blocks as a script.
+// Returns the exit status, which is non-zero if the script could not be written or any page failed.
+func export(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s export [OPTIONS] IMAGE_LOCATION...\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	formatPtr := fs.String("format", "", fmt.Sprintf("Script format: %s. Defaults to the extension of -o, or %s.", strings.Join(script.Formats, ", "), script.Markdown))
+	outputPtr := fs.String("o", "", "File the script is written to. Defaults to stdout.")
+	urlImagePtr := fs.Bool("url", false, "Use images from URLs instead of local files.")
+	splitPtr := fs.Bool("split", false, "Split double-page spreads into two pages.")
+	splitRatioPtr := fs.Float64("split-ratio", 1.2, "Width to height ratio above which an image is split with -split.")
+	configDirPtr := fs.String("config", "", "Directory used for the config, cache and history files. Overrides MTL_HOME.")
+	profilePtr := fs.String("profile", "", "Name of the config profile to use. Overrides MTL_PROFILE.")
+	fs.Parse(args)
+	if *configDirPtr != "" {
+		config.SetHome(*configDirPtr)
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		fs.Usage()
+		return 2
+	}
+	format := *formatPtr
+	if format == "" {
+		format = script.FormatOf(*outputPtr)
+	}
+
+	f := setupLogging()
+	defer f.Close()
+	log.Infof("Exporting script of: %v", paths)
+
+	var series *config.Series
+	if !*urlImagePtr {
+		var err error
+		if series, err = config.FindSeries(window.ChapterDir(paths)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	var cfg config.File
+	if err := config.Setup(config.Path(), *profilePtr, series, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if errs := config.Check(cfg); len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, "Your config has problems, run manga-translator-setup to fix them:")
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "  %v\n", err)
+		}
+		return 1
+	}
+
+	var images []imageW.TranslatorImage
+	for _, path := range paths {
+		img, err := imageW.Load(path, *urlImagePtr, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 1
+		}
+		if *splitPtr && img.IsSpread(float32(*splitRatioPtr)) {
+			images = append(images, img.SplitSpread(cfg.RightToLeft())...)
+			continue
+		}
+		images = append(images, img)
+	}
+
+	sess := session.New()
+	defer sess.Close()
+	status := 0
+	pages := make([]script.Page, len(images))
+	for i, img := range images {
+		fmt.Fprintf(os.Stderr, "Page %d/%d\n", i+1, len(images))
+		pages[i] = script.Page{Number: i + 1}
+		blocks, err := sess.Fetch(sess.Context(), cfg, img, nil)
+		if err != nil {
+			log.Errorf("Page %d failed: %v", i+1, err)
+			fmt.Fprintf(os.Stderr, "Page %d failed: %v\n", i+1, err)
+			pages[i].Status = err.Error()
+			status = 1
+			continue
+		}
+		pages[i].Blocks = blocks
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputPtr != "" {
+		out, err := os.Create(*outputPtr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer out.Close()
+		w = out
+	}
+	if err := script.Write(w, format, pages, cfg.RightToLeft()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return status
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"gioui.org/app"
 	"gioui.org/unit"
 	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/history"
 	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
 	"github.com/cameronkinsella/manga-translator/pkg/window"
 	log "github.com/sirupsen/logrus"
@@ -15,35 +17,58 @@ import (
 var maxDim float32 = 1000 // hard-coded
 
 func main() {
-	// Set up logging.
-	log.SetFormatter(&log.JSONFormatter{})
-	log.SetLevel(log.DebugLevel)
-
-	settings := config.Path()
-	logPath := filepath.Join(settings, "mtl-logrus.log")
-	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
-	if err == nil {
-		log.SetOutput(f)
-	} else {
-		log.Warning("Failed to log to file, using default stderr")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			os.Exit(export(os.Args[2:]))
+		case "cache":
+			os.Exit(cacheCommand(os.Args[2:]))
+		case "serve-cache":
+			os.Exit(serveCache(os.Args[2:]))
+		case "serve":
+			os.Exit(serve(os.Args[2:]))
+		}
 	}
-	defer f.Close()
 
 	// Parse flags.
 	urlImagePtr := flag.Bool("url", false, "Use an image from a URL instead of a local file.")
 	clipImagePtr := flag.Bool("clip", false, "Use an image from the clipboard.") // overrides url
+	spreadPtr := flag.Bool("spread", false, "Show pages in pairs, in right-to-left order.")
+	splitPtr := flag.Bool("split", false, "Split double-page spreads into two pages.")
+	splitRatioPtr := flag.Float64("split-ratio", 1.2, "Width to height ratio above which an image is split with -split.")
+	configDirPtr := flag.String("config", "", "Directory used for the config, cache and history files. Overrides MTL_HOME.")
+	profilePtr := flag.String("profile", "", "Name of the config profile to use. Overrides MTL_PROFILE.")
 	flag.Parse()
+	if *configDirPtr != "" {
+		config.SetHome(*configDirPtr)
+	}
+
+	f := setupLogging()
+	defer f.Close()
+
+	settings := config.Path()
+
 	log.Infof("Use URL image: %v", *urlImagePtr)
 	log.Infof("Use clipboard image: %v", *clipImagePtr)
+	log.Infof("Use spread view: %v", *spreadPtr)
+	log.Infof("Split spreads: %v", *splitPtr)
 
 	// Set up config, create new config if necessary.
 	var cfg config.File
-	config.Setup(settings, &cfg)
-
-	// Open/download selected image and get its info.
-	if len(flag.Args()) == 0 && !*clipImagePtr {
-		log.Fatal("No path or URL given.")
+	if err := config.Setup(settings, *profilePtr, nil, &cfg); err != nil {
+		log.Errorf("Invalid config: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	log.Infof("Using profile: %q", cfg.Profile)
+	if errs := config.Check(cfg); len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, "Your config has problems, translations will fail until they are fixed:")
+		for _, err := range errs {
+			log.Warningf("Invalid config: %v", err)
+			fmt.Fprintf(os.Stderr, "  %v\n", err)
+		}
 	}
+
 	var imgPath []string
 	if !*clipImagePtr {
 		imgPath = flag.Args()
@@ -53,36 +78,107 @@ func main() {
 		imgPath = append(imgPath, "clipboard")
 	}
 
-	if len(imgPath) == 0 {
-		log.Fatal("No images provided.")
-	}
-
-	var img []imageW.TranslatorImage
+	go func() {
+		var w *app.Window
+		if len(imgPath) == 0 {
+			// No images given, let the user re-open a recently read chapter.
+			log.Info("No path or URL given, showing history.")
+			w = app.NewWindow(
+				app.Title("Manga Translator"),
+				app.Size(unit.Dp(maxDim), unit.Dp(maxDim*0.75)),
+				app.MinSize(unit.Dp(600), unit.Dp(300)),
+			)
+			chapter, err := window.StartScreen(w, history.Recent())
+			if err != nil {
+				log.Fatal(err)
+			} else if chapter == nil {
+				os.Exit(0)
+			}
+			imgPath = chapter.Paths
+			*urlImagePtr = chapter.URL
+		}
 
-	for _, paths := range imgPath {
-		log.Debugf("Getting image info for: %v", imgPath)
-		newImage := imageW.Open(paths, *urlImagePtr, *clipImagePtr)
-		img = append(img, newImage)
-	}
+		// Use the settings of the series, if the chapter has a series file.
+		var series *config.Series
+		if !*urlImagePtr && !*clipImagePtr {
+			var err error
+			series, err = config.FindSeries(window.ChapterDir(imgPath))
+			if err != nil {
+				log.Errorf("Invalid series file: %v", err)
+				fmt.Fprintln(os.Stderr, err)
+			} else if series != nil {
+				log.Infof("Using series file: %v", series.Path())
+				if err := config.Setup(settings, *profilePtr, series, &cfg); err != nil {
+					log.Errorf("Invalid config: %v", err)
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+			}
+		}
 
-	// We need this ratio to scale the image down/up to the required starting size.
-	ratio := imageW.GetRatio(img[0].Dimensions, maxDim)
-	firstWidth := float32(img[0].Dimensions.Width)
-	firstHeight := float32(img[0].Dimensions.Height)
+		// Open/download selected image and get its info.
+		var img []imageW.TranslatorImage
+		var hashes []string
+		for _, paths := range imgPath {
+			log.Debugf("Getting image info for: %v", paths)
+			newImage := imageW.Open(paths, *urlImagePtr, *clipImagePtr)
+			hashes = append(hashes, newImage.Hash)
+			if *splitPtr && newImage.IsSpread(float32(*splitRatioPtr)) {
+				log.Infof("Splitting spread: %v", paths)
+				img = append(img, newImage.SplitSpread(cfg.RightToLeft())...)
+				continue
+			}
+			img = append(img, newImage)
+		}
 
-	go func() {
-		// Create new window.
-		w := app.NewWindow(
-			app.Title("Manga Translator"),
+		// We need this ratio to scale the image down/up to the required starting size.
+		ratio := imageW.GetRatio(img[0].Dimensions, maxDim)
+		firstWidth := float32(img[0].Dimensions.Width)
+		firstHeight := float32(img[0].Dimensions.Height)
+		if *spreadPtr && len(img) > 1 {
+			// Two pages are shown side by side.
+			ratio = imageW.GetRatio(imageW.Dimensions{Width: 2 * img[0].Dimensions.Width, Height: img[0].Dimensions.Height}, maxDim)
+			firstWidth *= 2
+		}
+		size := app.Size(unit.Dp(ratio*firstWidth), unit.Dp(ratio*firstHeight))
 
-			app.Size(unit.Dp(ratio*firstWidth), unit.Dp(ratio*firstHeight)),
-			app.MinSize(unit.Dp(600), unit.Dp(300)),
-		)
+		if w == nil {
+			// Create new window.
+			w = app.NewWindow(
+				app.Title("Manga Translator"),
+				size,
+				app.MinSize(unit.Dp(600), unit.Dp(300)),
+			)
+		} else {
+			w.Option(size)
+		}
 
-		if err := window.DrawFrame(w, img, cfg); err != nil {
+		opts := window.Options{
+			Spread:     *spreadPtr,
+			Split:      *splitPtr,
+			SplitRatio: float32(*splitRatioPtr),
+			Profile:    *profilePtr,
+			Series:     series,
+		}
+		if err := window.DrawFrame(w, img, cfg, opts, history.Lookup(imgPath, hashes, *urlImagePtr, *clipImagePtr)); err != nil {
 			log.Fatal(err)
 		}
 		os.Exit(0)
 	}()
 	app.Main()
 }
+
+// setupLogging logs to the log file in the state directory. Returns the log file, which should be closed on exit.
+func setupLogging() *os.File {
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetLevel(log.DebugLevel)
+
+	logPath := filepath.Join(config.StatePath(), "mtl-logrus.log")
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err == nil {
+		log.SetOutput(f)
+	} else {
+		log.Warning("Failed to log to file, using default stderr")
+	}
+	return f
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/api"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// serve runs a local REST API which detects and translates images. Returns the exit status.
+func serve(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve [OPTIONS]\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	addrPtr := fs.String("addr", "127.0.0.1:8081", "Address the server listens on.")
+	concurrencyPtr := fs.Int("concurrency", 2, "Maximum number of images which are detected and translated at the same time.")
+	tokenPtr := fs.String("token", "", "Token which clients must send as a bearer token. Defaults to MTL_API_TOKEN.")
+	noTokenPtr := fs.Bool("no-token", false, "Accept requests without a token.")
+	allowOriginPtr := fs.String("allow-origin", "", "Comma-separated origins which browsers may call the API from, e.g. \"https://example.com\". Requests from other origins are rejected.")
+	allowPrivatePtr := fs.Bool("allow-private-urls", false, "Download images from loopback and private network addresses.")
+	configDirPtr := fs.String("config", "", "Directory used for the config, cache and history files. Overrides MTL_HOME.")
+	profilePtr := fs.String("profile", "", "Name of the config profile to use. Overrides MTL_PROFILE.")
+	fs.Parse(args)
+	if *configDirPtr != "" {
+		config.SetHome(*configDirPtr)
+	}
+	if *concurrencyPtr < 1 {
+		fmt.Fprintln(os.Stderr, "-concurrency must be at least 1")
+		return 2
+	}
+	f := setupLogging()
+	defer f.Close()
+
+	var cfg config.File
+	if err := config.Setup(config.Path(), *profilePtr, nil, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	log.Infof("Using profile: %q", cfg.Profile)
+	if errs := config.Check(cfg); len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, "Your config has problems, run manga-translator-setup to fix them:")
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "  %v\n", err)
+		}
+		return 1
+	}
+	token := *tokenPtr
+	if token == "" {
+		token = os.Getenv("MTL_API_TOKEN")
+	}
+	if token == "" && !*noTokenPtr {
+		fmt.Fprintln(os.Stderr, "A token is required, give it with -token or MTL_API_TOKEN, or use -no-token")
+		return 2
+	}
+	var origins []string
+	for _, o := range strings.Split(*allowOriginPtr, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+
+	sess := session.New()
+	defer sess.Close()
+	srv := &http.Server{
+		Addr: *addrPtr,
+		Handler: api.Handler(sess, cfg, api.Options{
+			Concurrency:      *concurrencyPtr,
+			Token:            token,
+			NoToken:          token == "",
+			AllowOrigins:     origins,
+			AllowPrivateURLs: *allowPrivatePtr,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		// Requests may wait for a free slot before the image is detected and translated.
+		WriteTimeout: 5 * time.Minute,
+	}
+	log.Infof("API server listening on %v", *addrPtr)
+	fmt.Fprintf(os.Stderr, "API server listening on %v\n", *addrPtr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Errorf("API server stopped: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
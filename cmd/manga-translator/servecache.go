@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serveCache runs a cache server which shares the local cache with a team. Returns the exit status.
+func serveCache(args []string) int {
+	fs := flag.NewFlagSet("serve-cache", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve-cache [OPTIONS]\n\nOptions:\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	addrPtr := fs.String("addr", ":8080", "Address the server listens on.")
+	tokenPtr := fs.String("token", "", "Shared token which clients must send. Defaults to cache.token of the config, or MTL_CACHE_TOKEN.")
+	configDirPtr := fs.String("config", "", "Directory used for the config, cache and history files. Overrides MTL_HOME.")
+	fs.Parse(args)
+	if *configDirPtr != "" {
+		config.SetHome(*configDirPtr)
+	}
+	f := setupLogging()
+	defer f.Close()
+
+	var cfg config.File
+	if err := config.Setup(config.Path(), "", nil, &cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	token := *tokenPtr
+	if token == "" {
+		token = cfg.Cache.Token
+	}
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "A token is required, give it with -token or MTL_CACHE_TOKEN")
+		return 2
+	}
+
+	srv := &http.Server{
+		Addr:              *addrPtr,
+		Handler:           cache.Handler(token, cfg.Cache.MaxSizeBytes()),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      time.Minute,
+	}
+	log.Infof("Cache server listening on %v", *addrPtr)
+	fmt.Fprintf(os.Stderr, "Cache server listening on %v\n", *addrPtr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Errorf("Cache server stopped: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -6,6 +6,7 @@ require (
 	cloud.google.com/go/translate v1.2.0
 	cloud.google.com/go/vision v0.1.0
 	gioui.org v0.0.0-20220307121938-3e18a310af31
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/gonoto/notosans v0.0.0-20200703162533-d78fef05ce80
 	github.com/inancgumus/screen v0.0.0-20190314163918-06e984b86ed3
 	github.com/sirupsen/logrus v1.8.1
@@ -16,6 +17,7 @@ require (
 	google.golang.org/api v0.74.0
 	google.golang.org/genproto v0.0.0-20220414192740-2d67ff6cf2b4
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
 
 require (
@@ -23,7 +25,6 @@ require (
 	cloud.google.com/go/compute v1.6.0 // indirect
 	gioui.org/cpu v0.0.0-20210817075930-8d6a761490d2 // indirect
 	gioui.org/shader v1.0.6 // indirect
-	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/go-cmp v0.5.7 // indirect
@@ -50,5 +51,4 @@ require (
 	google.golang.org/grpc v1.45.0 // indirect
 	google.golang.org/protobuf v1.28.0 // indirect
 	gopkg.in/ini.v1 v1.66.4 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
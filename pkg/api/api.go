@@ -0,0 +1,337 @@
+package api
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
+	log "github.com/sirupsen/logrus"
+	"image"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxImageSize is the maximum size of an uploaded or downloaded image, in bytes.
+var maxImageSize int64 = 50 << 20
+
+// maxPixels is the maximum number of pixels of an image, checked before it is decoded.
+var maxPixels = 100_000_000
+
+// downloadTimeout is how long downloading an image from a URL may take.
+var downloadTimeout = 30 * time.Second
+
+// Options configure the API server.
+type Options struct {
+	Concurrency  int      // Maximum number of images which are detected and translated at the same time.
+	Token        string   // Requests must have the header "Authorization: Bearer <token>".
+	NoToken      bool     // Accept requests without a token. Every request is rejected if this is false and Token is blank.
+	AllowOrigins []string // Origins which browsers may call the API from, "*" allows any. Requests from other origins are rejected.
+	// Images may be downloaded from loopback, private and link-local addresses. Otherwise, any client of the API could
+	// make the server fetch URLs of the network it runs in.
+	AllowPrivateURLs bool
+}
+
+// Request is the JSON body of a translation request for an image URL.
+type Request struct {
+	URL            string `json:"url"`
+	Service        string `json:"service,omitempty"`        // Overrides translation.selectedService of the config.
+	TargetLanguage string `json:"targetLanguage,omitempty"` // Overrides translation.targetLanguage of the config.
+}
+
+// Response is the result of a translation request.
+type Response struct {
+	SHA256         string        `json:"sha256"`
+	Width          int           `json:"width"` // Dimensions of the image, which the vertices of the blocks are relative to.
+	Height         int           `json:"height"`
+	Service        string        `json:"service"`
+	TargetLanguage string        `json:"targetLanguage"`
+	Blocks         []cache.Block `json:"blocks"`
+}
+
+// errorResponse is the body of an error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// server detects and translates the images of API requests.
+type server struct {
+	sess   *session.Session
+	cfg    config.File
+	opts   Options
+	slots  chan struct{} // Limits the number of images which are processed at the same time.
+	client *http.Client  // Downloads the images given by URL.
+}
+
+// Handler serves the REST API, which detects and translates images with the given config and session:
+//
+//	POST /v1/translate
+//	    Translates an image, given as a multipart/form-data upload in the "image" field or as a Request in a JSON body.
+//	    The "service" and "targetLanguage" form fields or query parameters override the config. Responds with a Response.
+//
+// Requests from a browser are rejected unless their origin is allowed, since a form upload from any web page reaches
+// the server without a CORS preflight.
+func Handler(sess *session.Session, cfg config.File, opts Options) http.Handler {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	s := &server{
+		sess:   sess,
+		cfg:    cfg,
+		opts:   opts,
+		slots:  make(chan struct{}, opts.Concurrency),
+		client: downloadClient(opts.AllowPrivateURLs),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/translate", s.translate)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !allowed(opts.AllowOrigins, origin) {
+				log.Warningf("Rejected API request from origin %q", origin)
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin %q is not allowed", origin))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		if !opts.NoToken {
+			auth, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !bearer || opts.Token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(opts.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+		log.Infof("%s %s (%v)", r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+// translate handles a translation request.
+func (s *server) translate(w http.ResponseWriter, r *http.Request) {
+	req, data, err := readRequest(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cfg := s.cfg
+	if req.Service != "" {
+		cfg.Translation.SelectedService = req.Service
+		for _, service := range config.Services {
+			if strings.EqualFold(req.Service, service) {
+				cfg.Translation.SelectedService = service
+			}
+		}
+		// The configured target language is in the format of the configured service.
+		if cfg.Translation.SelectedService != s.cfg.Translation.SelectedService {
+			cfg.Translation.TargetLanguage = config.DefaultTargetLanguage(cfg.Translation.SelectedService)
+		}
+	}
+	if req.TargetLanguage != "" {
+		cfg.Translation.TargetLanguage = req.TargetLanguage
+	}
+	if errs := config.Validate(cfg); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		writeError(w, http.StatusBadRequest, errors.New(strings.Join(msgs, "; ")))
+		return
+	}
+
+	// Wait for a free slot, unless the client gives up first. Images are only downloaded and decoded in a slot, so
+	// the concurrency also limits the memory they use.
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-r.Context().Done():
+		return
+	}
+
+	if req.URL != "" {
+		log.Infof("Downloading image: %v", req.URL)
+		data, err = s.download(r, req.URL)
+	}
+	var img imageW.TranslatorImage
+	if err == nil {
+		img, err = decode(data)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	blocks, err := s.sess.Fetch(r.Context(), cfg, img, nil)
+	if err != nil {
+		log.Errorf("Translation request failed: %v", err)
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{
+		SHA256:         img.Hash,
+		Width:          img.Dimensions.Width,
+		Height:         img.Dimensions.Height,
+		Service:        cfg.Translation.SelectedService,
+		TargetLanguage: cfg.Translation.TargetLanguage,
+		Blocks:         cache.ToBlocks(blocks),
+	})
+}
+
+// readRequest reads a translation request. The image file is returned if it was uploaded, otherwise the request has
+// a URL.
+func readRequest(w http.ResponseWriter, r *http.Request) (Request, []byte, error) {
+	var req Request
+	var data []byte
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if req.URL == "" {
+			return req, nil, errors.New(`"url" is required`)
+		}
+	case "multipart/form-data":
+		f, _, err := r.FormFile("image")
+		if err != nil {
+			return req, nil, fmt.Errorf(`"image" is required: %w`, err)
+		}
+		defer f.Close()
+		if data, err = io.ReadAll(f); err != nil {
+			return req, nil, err
+		}
+	default:
+		return req, nil, errors.New("send an image as multipart/form-data, or a URL as application/json")
+	}
+
+	// Form fields and query parameters override the JSON body.
+	if service := r.FormValue("service"); service != "" {
+		req.Service = service
+	}
+	if language := r.FormValue("targetLanguage"); language != "" {
+		req.TargetLanguage = language
+	}
+	return req, data, nil
+}
+
+// downloadClient returns the client which downloads the images given by URL. Unless allowPrivate is true, it refuses to
+// connect to loopback, private, link-local and other non-public addresses. The addresses are checked when connecting,
+// after the host name was resolved and for every redirect, so a public host name can't lead to a private address.
+// Proxies are not used, since the address of the proxy would be checked instead of the address of the image host.
+func downloadClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: downloadTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !public(ip) {
+				return fmt.Errorf("%v is not a public address", ip)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: downloadTimeout, Transport: transport}
+}
+
+// public returns if the given address can be reached from the internet.
+func public(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is used by carrier-grade NAT, its addresses are not public either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// download returns the image at the given URL. The download is cancelled with the given request.
+func (s *server) download(r *http.Request, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http or https URL", rawURL)
+	}
+	dlReq, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(dlReq)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %v", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	if int64(len(data)) > maxImageSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageSize)
+	}
+	return data, nil
+}
+
+// decode decodes the given image, after checking that its dimensions are small enough to decode it.
+func decode(data []byte) (imageW.TranslatorImage, error) {
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return imageW.TranslatorImage{}, fmt.Errorf("unable to decode image: %w", err)
+	}
+	if imgCfg.Width <= 0 || imgCfg.Height <= 0 || imgCfg.Width > maxPixels/imgCfg.Height {
+		return imageW.TranslatorImage{}, fmt.Errorf("image of %dx%d pixels is too large", imgCfg.Width, imgCfg.Height)
+	}
+	return imageW.Decode(data)
+}
+
+// allowed returns if the given origin is one of the allowed origins.
+func allowed(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON writes the given value as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the given error as a JSON response with the given status code.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
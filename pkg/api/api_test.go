@@ -0,0 +1,369 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// credentialsPath is the path of a service account key which is valid as far as the config validation can tell.
+var credentialsPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mtl-api-test")
+	if err != nil {
+		panic(err)
+	}
+	// The paths are resolved once, so every test uses the same cache.
+	config.SetHome(dir)
+	credentialsPath = filepath.Join(dir, "credentials.json")
+	key := `{"type": "service_account", "client_email": "test@example.com", "private_key": "not a key"}`
+	if err := os.WriteFile(credentialsPath, []byte(key), 0600); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+const testToken = "secret"
+
+// testConfig returns a valid config, the APIs are never reached since the test images are cached.
+func testConfig() config.File {
+	var cfg config.File
+	cfg.CloudVision.CredentialsPath = credentialsPath
+	cfg.Translation.SelectedService = "google"
+	cfg.Translation.TargetLanguage = "en"
+	cfg.Translation.DeepL.APIKey = "deepl-key"
+	return cfg
+}
+
+// testImage returns a PNG image of the given size, which differs for each seed.
+func testImage(t *testing.T, w, h int, seed uint8) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x) + seed, G: uint8(y), B: seed, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// cacheImage adds the given image to the cache with a single block translated with the given service and language.
+func cacheImage(t *testing.T, data []byte, service, language string) {
+	t.Helper()
+	img, err := imageW.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := []detect.TextBlock{{
+		Text:       "原文",
+		Translated: "Translated with " + service,
+		Vertices:   []*pb.Vertex{{X: 1, Y: 1}, {X: 10, Y: 1}, {X: 10, Y: 10}, {X: 1, Y: 10}},
+	}}
+	key := cache.Image{Hash: img.Hash, PHash: img.PHash, Width: img.Dimensions.Width, Height: img.Dimensions.Height}
+	if err := cache.Add(key, service, language, "", blocks, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// upload returns a request which uploads the given image with the given form fields.
+func upload(t *testing.T, data []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("image", "page.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/v1/translate", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	return r
+}
+
+// urlRequest returns a request to translate the image at the given URL.
+func urlRequest(rawURL string) *http.Request {
+	body, _ := json.Marshal(Request{URL: rawURL})
+	r := httptest.NewRequest(http.MethodPost, "/v1/translate", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	return r
+}
+
+// serve returns the response of the given handler to the given request.
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// decodeResponse returns the translation response of a successful request.
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) Response {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestToken(t *testing.T) {
+	data := testImage(t, 40, 60, 1)
+	cacheImage(t, data, "google", "en")
+	h := Handler(session.New(), testConfig(), Options{Token: testToken})
+
+	for name, auth := range map[string]string{
+		"missing":      "",
+		"wrong":        "Bearer wrong",
+		"not a bearer": testToken,
+	} {
+		r := upload(t, data, nil)
+		r.Header.Set("Authorization", auth)
+		if w := serve(h, r); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: got status %d, want 401", name, w.Code)
+		}
+	}
+
+	resp := decodeResponse(t, serve(h, upload(t, data, nil)))
+	if len(resp.Blocks) != 1 || resp.Width != 40 || resp.Height != 60 || resp.Service != "google" {
+		t.Errorf("got response %+v, want the cached blocks of the image", resp)
+	}
+
+	// Without a token, every request is rejected unless NoToken is set.
+	if w := serve(Handler(session.New(), testConfig(), Options{}), upload(t, data, nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d without a configured token, want 401", w.Code)
+	}
+	r := upload(t, data, nil)
+	r.Header.Del("Authorization")
+	decodeResponse(t, serve(Handler(session.New(), testConfig(), Options{NoToken: true}), r))
+}
+
+func TestOrigin(t *testing.T) {
+	data := testImage(t, 40, 60, 2)
+	cacheImage(t, data, "google", "en")
+	h := Handler(session.New(), testConfig(), Options{Token: testToken, AllowOrigins: []string{"https://reader.example.com"}})
+
+	r := upload(t, data, nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	if w := serve(h, r); w.Code != http.StatusForbidden {
+		t.Errorf("got status %d for another origin, want 403", w.Code)
+	}
+
+	r = upload(t, data, nil)
+	r.Header.Set("Origin", "https://reader.example.com")
+	w := serve(h, r)
+	decodeResponse(t, w)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://reader.example.com" {
+		t.Errorf("got Access-Control-Allow-Origin %q, want the origin", got)
+	}
+
+	// Preflight requests are sent without the token.
+	for origin, want := range map[string]int{
+		"https://reader.example.com": http.StatusNoContent,
+		"https://evil.example.com":   http.StatusForbidden,
+	} {
+		r := httptest.NewRequest(http.MethodOptions, "/v1/translate", nil)
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		w := serve(h, r)
+		if w.Code != want {
+			t.Errorf("preflight from %s: got status %d, want %d", origin, w.Code, want)
+		}
+		if want == http.StatusNoContent && !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "Authorization") {
+			t.Errorf("preflight from %s: the Authorization header is not allowed", origin)
+		}
+	}
+
+	// Requests which are not sent by a browser have no origin.
+	if w := serve(Handler(session.New(), testConfig(), Options{Token: testToken}), upload(t, data, nil)); w.Code != http.StatusOK {
+		t.Errorf("got status %d without an origin, want 200", w.Code)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	data := testImage(t, 40, 60, 3)
+	cacheImage(t, data, "google", "de")
+	cacheImage(t, data, "deepL", "EN-US")
+	h := Handler(session.New(), testConfig(), Options{Token: testToken})
+
+	resp := decodeResponse(t, serve(h, upload(t, data, map[string]string{"targetLanguage": "de"})))
+	if resp.TargetLanguage != "de" || resp.Blocks[0].Translated != "Translated with google" {
+		t.Errorf("got response %+v, want the German translation", resp)
+	}
+	// The configured target language is in the format of Google, so the default of DeepL is used.
+	resp = decodeResponse(t, serve(h, upload(t, data, map[string]string{"service": "deepl"})))
+	if resp.Service != "deepL" || resp.TargetLanguage != "EN-US" || resp.Blocks[0].Translated != "Translated with deepL" {
+		t.Errorf("got response %+v, want the DeepL translation", resp)
+	}
+
+	for name, fields := range map[string]map[string]string{
+		"service":  {"service": "bing"},
+		"language": {"targetLanguage": "english"},
+	} {
+		w := serve(h, upload(t, data, fields))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "translation.") {
+			t.Errorf("invalid %s: got status %d, %s, want 400 with the invalid field", name, w.Code, w.Body)
+		}
+	}
+
+	noDeepL := testConfig()
+	noDeepL.Translation.DeepL.APIKey = ""
+	w := serve(Handler(session.New(), noDeepL, Options{Token: testToken}), upload(t, data, map[string]string{"service": "deepL"}))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "translation.deepL.apiKey") {
+		t.Errorf("DeepL without a key: got status %d, %s, want 400", w.Code, w.Body)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	h := Handler(session.New(), testConfig(), Options{Token: testToken})
+	defer func(size int64, pixels int) { maxImageSize, maxPixels = size, pixels }(maxImageSize, maxPixels)
+
+	maxImageSize = 1 << 10
+	w := serve(h, upload(t, testImage(t, 200, 300, 4), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("image larger than the size limit: got status %d, want 400", w.Code)
+	}
+
+	maxImageSize, maxPixels = 50<<20, 40*60-1
+	w = serve(h, upload(t, testImage(t, 40, 60, 5), nil))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "too large") {
+		t.Errorf("image with too many pixels: got status %d, %s, want 400", w.Code, w.Body)
+	}
+
+	for name, body := range map[string]string{
+		"not an image": "hello",
+		"empty":        "",
+	} {
+		if w := serve(h, upload(t, []byte(body), nil)); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", name, w.Code)
+		}
+	}
+}
+
+func TestPrivateURL(t *testing.T) {
+	data := testImage(t, 40, 60, 6)
+	cacheImage(t, data, "google", "en")
+	var requests int
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(data)
+	}))
+	defer host.Close()
+
+	h := Handler(session.New(), testConfig(), Options{Token: testToken})
+	for _, rawURL := range []string{host.URL + "/page.png", "file:///etc/passwd", "http://169.254.169.254/latest/meta-data"} {
+		w := serve(h, urlRequest(rawURL))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want 400", rawURL, w.Code)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("the image was downloaded from a loopback address %d times", requests)
+	}
+
+	h = Handler(session.New(), testConfig(), Options{Token: testToken, AllowPrivateURLs: true})
+	decodeResponse(t, serve(h, urlRequest(host.URL+"/page.png")))
+	if requests != 1 {
+		t.Errorf("got %d downloads with private URLs allowed, want 1", requests)
+	}
+}
+
+func TestPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.10":     false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::ffff:10.0.0.1":  false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := public(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("public(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	data := testImage(t, 40, 60, 7)
+	cacheImage(t, data, "google", "en")
+	var (
+		mu             sync.Mutex
+		active, maxNow int
+	)
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxNow {
+			maxNow = active
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		w.Write(data)
+	}))
+	defer host.Close()
+
+	h := Handler(session.New(), testConfig(), Options{Token: testToken, Concurrency: 1, AllowPrivateURLs: true})
+	var wg sync.WaitGroup
+	codes := make([]int, 4)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = serve(h, urlRequest(host.URL+"/page.png")).Code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: got status %d, want 200", i, code)
+		}
+	}
+	if maxNow != 1 {
+		t.Errorf("%d images were downloaded at the same time, want 1", maxNow)
+	}
+}
@@ -1,107 +1,311 @@
 package cache
 
 import (
+	"bytes"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"github.com/cameronkinsella/manga-translator/pkg/config"
 	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
 	log "github.com/sirupsen/logrus"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 type data struct {
-	Hash    string
-	Service string
-	Blocks  []detect.TextBlock
+	Hash     string
+	Service  string
+	Language string // Target language of the translations. Blank for entries added before it was recorded, only their text is used.
+	Glossary string // Digest of the glossary used to translate, see config.File.GlossaryDigest. Blank if there was none.
+	Blocks   []detect.TextBlock
+	Updated  time.Time // When the entry was added or imported. Zero for entries added before it was recorded.
+	Edited   bool      // The translations were changed by hand, e.g. in an imported file.
+	Used     time.Time // When the entry was last used, at most usedInterval ago. Zero if it was never used.
+	PHash    uint64    // Perceptual hash of the image. Only set with Width and Height.
+	Width    int       // Dimensions of the image the text was detected in. Zero for entries added before they were recorded.
+	Height   int
 }
 
 var mu sync.Mutex
 
+// formatHeader is written before the entries of the cache file. Each entry is encoded separately, so the entries
+// before a corrupted one can still be read. Files without the header contain a single []data.
+const formatHeader = "mtl-cache/2"
+
+// usedInterval is how often the time an entry was last used is updated, so the cache is not written every time it is
+// read.
+var usedInterval = time.Hour
+
+// cachePath returns the path of the cache file.
+func cachePath() string {
+	return filepath.Join(config.CachePath(), "mtl-cache.bin")
+}
+
 // read creates a cache if one doesn't already exist, reads the data from the cache,
 // and returns it as a slice of cache Data.
-func read() []data {
-	var cacheData []data
-
-	cachePath := filepath.Join(config.Path(), "mtl-cache.bin")
-	cacheFile, err := os.Open(cachePath)
+func read() ([]data, error) {
+	cacheData, err := decode(cachePath())
 	if errors.Is(err, os.ErrNotExist) {
 		// handle the case where the file doesn't exist
-		cacheFile, err = os.Create(cachePath)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer cacheFile.Close()
+		return nil, write(nil)
+	} else if err != nil {
+		return nil, fmt.Errorf(`cache is corrupted, run "manga-translator cache verify -salvage" to keep the readable entries: %w`, err)
+	}
+	return cacheData, nil
+}
+
+// decode reads the entries of the cache file at the given path. If the file is corrupted, the entries before the
+// corrupted one are returned with the error.
+func decode(path string) ([]data, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
 
-		enc := gob.NewEncoder(cacheFile)
-		if err := enc.Encode(cacheData); err != nil {
-			log.Fatalf("Cache creation failed: %v", err)
+	var cacheData []data
+	dec := gob.NewDecoder(bytes.NewReader(b))
+	var header string
+	if err := dec.Decode(&header); err != nil || header != formatHeader {
+		// Written before the header was added.
+		err := gob.NewDecoder(bytes.NewReader(b)).Decode(&cacheData)
+		return cacheData, err
+	}
+	for {
+		var d data
+		if err := dec.Decode(&d); err == io.EOF {
+			return cacheData, nil
+		} else if err != nil {
+			return cacheData, err
 		}
+		cacheData = append(cacheData, d)
+	}
+}
 
-		return cacheData
-	} else if err != nil {
-		log.Fatal(err)
+// write replaces the data in the cache with the given data.
+func write(cacheData []data) error {
+	if err := encode(cachePath(), cacheData); err != nil {
+		return fmt.Errorf("cache write failed: %w", err)
+	}
+	return nil
+}
+
+// encode writes the given data to the file at the given path. The data is written to a temporary file first,
+// so the file is never left half written.
+func encode(path string, cacheData []data) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "mtl-cache-*.tmp")
+	if err != nil {
+		return err
 	}
-	defer cacheFile.Close()
+	defer os.Remove(f.Name())
 
-	dec := gob.NewDecoder(cacheFile)
-	if err := dec.Decode(&cacheData); err != nil {
-		log.Fatal(err)
+	enc := gob.NewEncoder(f)
+	err = enc.Encode(formatHeader)
+	for i := 0; i < len(cacheData) && err == nil; i++ {
+		err = enc.Encode(cacheData[i])
 	}
-	return cacheData
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Image identifies an image in the cache.
+type Image struct {
+	Hash   string // sha256 hash of the image file.
+	PHash  uint64 // Perceptual hash of the decoded image, see imageW.PerceptualHash.
+	Width  int    // Dimensions of the image the text is detected in, which the text block vertices are relative to.
+	Height int
 }
 
-// Check returns the text blocks of the given image hash and its translation service if it is in cache, otherwise returns nil and the given service.
-func Check(h string, service string) (blocks []detect.TextBlock, translateOnly bool) {
+// maxAspectDifference is how much the aspect ratio of similar images may differ, as a fraction.
+var maxAspectDifference = 0.02
+
+// maxScale is how many times larger than the other one of two similar images may be. The blocks detected in a much
+// smaller image can't be scaled up precisely, and a thumbnail's text may not have been legible at all.
+var maxScale = 2.0
+
+// Check returns the text blocks of the given image if it is in cache. If they were only cached with a different
+// translation service, target language or glossary, or with an unknown target language, translateOnly is true and
+// only the detected text can be used.
+// If maxDistance is not negative and the image is not in cache, the blocks of the most similar image are returned
+// instead: one whose perceptual hash differs by at most maxDistance bits, with the blocks rescaled to the size of the
+// given image. Returns nil if the image is not in cache.
+func Check(img Image, service, language, glossary string, maxDistance int) (blocks []detect.TextBlock, translateOnly bool, err error) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	cacheData := read()
+	cacheData, err := read()
+	if err != nil {
+		return nil, false, err
+	}
 
 	var existingBlocks []detect.TextBlock
-	for _, data := range cacheData {
-		if h == data.Hash && data.Service == service {
+	// Most similar entries with the same and with any translation service and target language.
+	similar, similarAny := -1, -1
+	distance, distanceAny := maxDistance+1, maxDistance+1
+	for i, data := range cacheData {
+		// Entries without a language were added before it was recorded, so their target language is unknown and
+		// only their detected text is reused.
+		sameTranslation := data.Service == service && data.Language == language && data.Glossary == glossary
+		if img.Hash == data.Hash && sameTranslation {
 			log.Info("Image found in cache, skipping API requests.")
-			return data.Blocks, false
-		} else if h == data.Hash {
+			cacheData[i].use(cacheData)
+			return data.Blocks, false, nil
+		} else if img.Hash == data.Hash {
 			existingBlocks = data.Blocks
+		} else if maxDistance >= 0 && data.similarTo(img) {
+			d := imageW.HashDistance(img.PHash, data.PHash)
+			if sameTranslation && d < distance {
+				similar, distance = i, d
+			}
+			if d < distanceAny {
+				similarAny, distanceAny = i, d
+			}
 		}
 	}
 
 	// Check if we found text blocks with the wrong service.
 	if existingBlocks != nil {
 		log.Info("Image text found in cache, performing new translation requests.")
-		return existingBlocks, true
+		return existingBlocks, true, nil
+	}
+	if similar >= 0 {
+		log.Infof("Similar image found in cache (distance %d), skipping API requests. sha256:%v", distance, cacheData[similar].Hash)
+		cacheData[similar].use(cacheData)
+		return cacheData[similar].rescale(img), false, nil
+	}
+	if similarAny >= 0 {
+		log.Infof("Similar image text found in cache (distance %d), performing new translation requests. sha256:%v", distanceAny, cacheData[similarAny].Hash)
+		return cacheData[similarAny].rescale(img), true, nil
 	}
 
 	log.Info("Image not found in cache, performing API requests.")
-	return nil, false
+	return nil, false, nil
+}
+
+// use records that the entry was used, unless it was already recorded in the last usedInterval. The entry must be an
+// element of the given data, which is written to the cache if it changed. The entry can still be used if the cache
+// can't be written, so failures are only logged.
+func (d *data) use(cacheData []data) {
+	if time.Since(d.Used) > usedInterval {
+		d.Used = time.Now()
+		if err := write(cacheData); err != nil {
+			log.Warningf("Unable to record cache use: %v", err)
+		}
+	}
+}
+
+// similarTo returns if the entry has a perceptual hash and dimensions which can be compared to the given image,
+// their aspect ratios are about the same, and neither is more than maxScale times larger than the other.
+// Hashes of blank or nearly uniform pages are never compared, see imageW.Distinctive.
+func (d data) similarTo(img Image) bool {
+	if d.Width <= 0 || d.Height <= 0 || img.Width <= 0 || img.Height <= 0 {
+		return false
+	}
+	if !imageW.Distinctive(d.PHash) || !imageW.Distinctive(img.PHash) {
+		return false
+	}
+	aspect := float64(d.Width) / float64(d.Height)
+	if math.Abs(float64(img.Width)/float64(img.Height)-aspect) > aspect*maxAspectDifference {
+		return false
+	}
+	scale := float64(img.Width) / float64(d.Width)
+	return scale <= maxScale && scale >= 1/maxScale
+}
+
+// rescale returns a copy of the blocks of the entry, with the vertices moved from the dimensions of the entry to the
+// dimensions of the given image.
+func (d data) rescale(img Image) []detect.TextBlock {
+	blocks := make([]detect.TextBlock, len(d.Blocks))
+	for i, b := range d.Blocks {
+		blocks[i] = b
+		blocks[i].Vertices = make([]*pb.Vertex, len(b.Vertices))
+		for j, v := range b.Vertices {
+			blocks[i].Vertices[j] = &pb.Vertex{
+				X: int32(math.Round(float64(v.X) * float64(img.Width) / float64(d.Width))),
+				Y: int32(math.Round(float64(v.Y) * float64(img.Height) / float64(d.Height))),
+			}
+		}
+	}
+	return blocks
 }
 
-// Add adds a new entry to the cache.
-func Add(h string, service string, blocks []detect.TextBlock) {
+// Add adds a new entry to the cache. "glossary" is the digest of the glossary used to translate.
+// If maxSize is positive, the least recently used entries are removed until the cache is at most maxSize bytes.
+func Add(img Image, service, language, glossary string, blocks []detect.TextBlock, maxSize int64) error {
 	mu.Lock()
 	defer mu.Unlock()
 
-	log.Debugf("Adding new image to cache. sha256:%v", h)
-	cacheData := read()
-
-	cachePath := filepath.Join(config.Path(), "mtl-cache.bin")
-	cacheFile, err := os.OpenFile(cachePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	log.Debugf("Adding new image to cache. sha256:%v", img.Hash)
+	cacheData, err := read()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer cacheFile.Close()
 
+	now := time.Now()
 	newData := data{
-		Hash:    h,
-		Service: service,
-		Blocks:  blocks,
+		Hash:     img.Hash,
+		Service:  service,
+		Language: language,
+		Glossary: glossary,
+		Blocks:   blocks,
+		Updated:  now,
+		Used:     now,
+		PHash:    img.PHash,
+		Width:    img.Width,
+		Height:   img.Height,
+	}
+	if !imageW.Distinctive(img.PHash) {
+		// It would only match other blank pages.
+		newData.PHash, newData.Width, newData.Height = 0, 0, 0
 	}
 	cacheData = append(cacheData, newData)
-	enc := gob.NewEncoder(cacheFile)
-	if err := enc.Encode(cacheData); err != nil {
-		log.Fatalf("Cache write failed: %v", err)
+	cacheData, _ = evict(cacheData, maxSize)
+	return write(cacheData)
+}
+
+// Match is a cached text block which contains the searched text.
+type Match struct {
+	Hash  string // Hash of the image which contains the block.
+	Index int    // Index of the block in the text blocks of the image.
+	Block detect.TextBlock
+}
+
+// Search returns the cached text blocks whose original or translated text contains the given text, ignoring case.
+// If an image was cached with several translation services or languages, only the first matching entry is used.
+func Search(query string) ([]Match, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cacheData, err := read()
+	if err != nil {
+		return nil, err
+	}
+	var matches []Match
+	searched := map[string]bool{}
+	for _, data := range cacheData {
+		if searched[data.Hash] {
+			continue
+		}
+		found := false
+		for i, b := range data.Blocks {
+			if b.Contains(query) {
+				matches = append(matches, Match{Hash: data.Hash, Index: i, Block: b})
+				found = true
+			}
+		}
+		if found {
+			searched[data.Hash] = true
+		}
 	}
+	return matches, nil
 }
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mtl-cache-test")
+	if err != nil {
+		panic(err)
+	}
+	// The paths are resolved once, so every test uses the same directory and starts with resetCache.
+	config.SetHome(dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// resetCache removes the cache file, so the test starts with an empty cache.
+func resetCache(t *testing.T) {
+	t.Helper()
+	for _, path := range []string{cachePath(), cachePath() + ".corrupt"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readCacheFile returns the data in the cache file.
+func readCacheFile(t *testing.T) []data {
+	t.Helper()
+	cacheData, err := decode(cachePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cacheData
+}
+
+func TestSimilarTo(t *testing.T) {
+	cached := data{PHash: 0xff00ff00ff00ff00, Width: 1000, Height: 1500}
+	tests := []struct {
+		name string
+		img  Image
+		want bool
+	}{
+		{"same size", Image{Width: 1000, Height: 1500}, true},
+		{"half size", Image{Width: 500, Height: 750}, true},
+		{"twice the size", Image{Width: 2000, Height: 3000}, true},
+		{"aspect within 2%", Image{Width: 1000, Height: 1480}, true},
+		{"aspect over 2%", Image{Width: 1000, Height: 1450}, false},
+		{"spread", Image{Width: 2000, Height: 1500}, false},
+		{"thumbnail", Image{Width: 200, Height: 300}, false},
+		{"much larger", Image{Width: 4000, Height: 6000}, false},
+		{"unknown size", Image{}, false},
+	}
+	for _, tt := range tests {
+		tt.img.PHash = cached.PHash
+		if got := cached.similarTo(tt.img); got != tt.want {
+			t.Errorf("%s: similarTo(%+v) = %v, want %v", tt.name, tt.img, got, tt.want)
+		}
+	}
+
+	if (data{PHash: cached.PHash}).similarTo(Image{PHash: cached.PHash, Width: 1000, Height: 1500}) {
+		t.Error("entry without dimensions is similar to an image")
+	}
+	// Blank and mostly white pages have hardly any bits set, whatever is on them.
+	for _, h := range []uint64{0, 0x8000000000000000} {
+		blank := data{PHash: h, Width: 1000, Height: 1500}
+		if blank.similarTo(Image{PHash: h, Width: 1000, Height: 1500}) {
+			t.Errorf("entry with hash %016x is similar to an image with the same hash", h)
+		}
+	}
+}
+
+func TestRescale(t *testing.T) {
+	cached := data{Width: 1000, Height: 1500, Blocks: []detect.TextBlock{{
+		Text:     "原文",
+		Vertices: []*pb.Vertex{{X: 100, Y: 200}, {X: 301, Y: 200}, {X: 301, Y: 451}, {X: 100, Y: 451}},
+	}}}
+
+	got := cached.rescale(Image{Width: 500, Height: 750})
+	want := []*pb.Vertex{{X: 50, Y: 100}, {X: 151, Y: 100}, {X: 151, Y: 226}, {X: 50, Y: 226}}
+	for i, v := range got[0].Vertices {
+		if v.X != want[i].X || v.Y != want[i].Y {
+			t.Errorf("vertex %d: got (%d, %d), want (%d, %d)", i, v.X, v.Y, want[i].X, want[i].Y)
+		}
+	}
+	if got[0].Text != "原文" {
+		t.Errorf("got text %q, want the text of the cached block", got[0].Text)
+	}
+	if cached.Blocks[0].Vertices[0].X != 100 {
+		t.Error("the cached blocks were changed")
+	}
+}
+
+func TestCheckSimilar(t *testing.T) {
+	resetCache(t)
+	block := []detect.TextBlock{{
+		Text:       "原文",
+		Translated: "Hello",
+		Vertices:   []*pb.Vertex{{X: 100, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 300}, {X: 100, Y: 300}},
+	}}
+	cached := Image{Hash: testHash("a"), PHash: 0xff00ff00ff00ff00, Width: 1000, Height: 1500}
+	if err := Add(cached, "google", "en", "", block, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Differs from the cached image in 3 bits.
+	similar := Image{Hash: testHash("b"), PHash: 0xff00ff00ff00ff07, Width: 500, Height: 750}
+	tests := []struct {
+		name          string
+		img           Image
+		service       string
+		maxDistance   int
+		found         bool
+		translateOnly bool
+	}{
+		{"within the distance", similar, "google", 4, true, false},
+		{"at the distance", similar, "google", 3, true, false},
+		{"over the distance", similar, "google", 2, false, false},
+		{"disabled", similar, "google", -1, false, false},
+		{"other service", similar, "deepL", 4, true, true},
+		{"other aspect ratio", Image{Hash: testHash("c"), PHash: similar.PHash, Width: 1000, Height: 1000}, "google", 64, false, false},
+		{"thumbnail", Image{Hash: testHash("d"), PHash: similar.PHash, Width: 100, Height: 150}, "google", 64, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, translateOnly, err := Check(tt.img, tt.service, "en", "", tt.maxDistance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found := blocks != nil; found != tt.found || translateOnly != tt.translateOnly {
+				t.Fatalf("got found %v, translateOnly %v, want %v, %v", found, translateOnly, tt.found, tt.translateOnly)
+			}
+			if found := blocks != nil; found && blocks[0].Vertices[2].Y != 150 {
+				t.Errorf("blocks were not rescaled to the image: %v", blocks[0].Vertices)
+			}
+		})
+	}
+}
+
+func TestCheckBlankPages(t *testing.T) {
+	resetCache(t)
+	block := []detect.TextBlock{{
+		Text:       "原文",
+		Translated: "Hello",
+		Vertices:   []*pb.Vertex{{X: 100, Y: 100}, {X: 200, Y: 100}, {X: 200, Y: 300}, {X: 100, Y: 300}},
+	}}
+	// Hashes of two different mostly white pages, see imageW.Distinctive.
+	if err := Add(Image{Hash: testHash("a"), PHash: 0x8000000000000000, Width: 1000, Height: 1500}, "google", "en", "", block, 0); err != nil {
+		t.Fatal(err)
+	}
+	blocks, _, err := Check(Image{Hash: testHash("b"), PHash: 0x0000001000000200, Width: 1000, Height: 1500}, "google", "en", "", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks != nil {
+		t.Errorf("got the blocks of another mostly white page: %v", blocks)
+	}
+	if got := readCacheFile(t); got[0].PHash != 0 || got[0].Width != 0 {
+		t.Errorf("stored hash %016x and width %d of a mostly white page, want none", got[0].PHash, got[0].Width)
+	}
+}
+
+func TestCheckGlossary(t *testing.T) {
+	resetCache(t)
+	img := Image{Hash: testHash("a")}
+	block := []detect.TextBlock{{
+		Text:       "原文",
+		Translated: "Hello",
+		Vertices:   []*pb.Vertex{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+	}}
+	if err := Add(img, "google", "en", "0123456789abcdef", block, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		glossary      string
+		translateOnly bool
+	}{
+		{"0123456789abcdef", false},
+		{"fedcba9876543210", true},
+		{"", true},
+	} {
+		blocks, translateOnly, err := Check(img, "google", "en", tt.glossary, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blocks == nil || translateOnly != tt.translateOnly {
+			t.Errorf("glossary %q: got blocks %v, translateOnly %v, want translateOnly %v", tt.glossary, blocks, translateOnly, tt.translateOnly)
+		}
+	}
+}
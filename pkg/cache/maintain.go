@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// Stats are the number of entries in the cache and their size.
+type Stats struct {
+	Size    int64 // Size of the cache file in bytes.
+	Entries int
+	Groups  []GroupStats // Entries by translation service and target language.
+}
+
+// GroupStats are the number of entries with the same translation service and target language, and their size.
+type GroupStats struct {
+	Service  string
+	Language string // Blank for entries added before the target language was recorded.
+	Entries  int
+	Size     int64 // Approximate size of the entries in bytes.
+}
+
+// PruneOptions select the entries which are removed by Prune. Entries matching any of the options are removed.
+type PruneOptions struct {
+	Hashes    []string      // Hashes of the images whose entries are removed.
+	OlderThan time.Duration // Remove the entries which were added longer ago than this, if positive.
+	MaxSize   int64         // Remove the least recently used entries until the cache is at most this many bytes, if positive.
+}
+
+// Inspect returns the statistics of the cache, which are empty if there is no cache file.
+func Inspect() (Stats, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	info, err := os.Stat(cachePath())
+	if os.IsNotExist(err) {
+		return Stats{}, nil
+	} else if err != nil {
+		return Stats{}, err
+	}
+	cacheData, err := decode(cachePath())
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{Size: info.Size(), Entries: len(cacheData)}
+	groups := map[[2]string]*GroupStats{}
+	for i, size := range entrySizes(cacheData) {
+		d := cacheData[i]
+		key := [2]string{d.Service, d.Language}
+		if groups[key] == nil {
+			groups[key] = &GroupStats{Service: d.Service, Language: d.Language}
+		}
+		groups[key].Entries++
+		groups[key].Size += size
+	}
+	for _, g := range groups {
+		stats.Groups = append(stats.Groups, *g)
+	}
+	sort.Slice(stats.Groups, func(i, j int) bool {
+		a, b := stats.Groups[i], stats.Groups[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Language < b.Language
+	})
+	return stats, nil
+}
+
+// Prune removes the entries selected by the given options from the cache. Returns the number of removed entries.
+func Prune(opts PruneOptions) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cacheData, err := decode(cachePath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	remove := map[string]bool{}
+	for _, h := range opts.Hashes {
+		remove[h] = true
+	}
+	var kept []data
+	for _, d := range cacheData {
+		// Entries without a time were added before it was recorded, their age is unknown.
+		old := opts.OlderThan > 0 && !d.Updated.IsZero() && time.Since(d.Updated) > opts.OlderThan
+		if !remove[d.Hash] && !old {
+			kept = append(kept, d)
+		}
+	}
+	kept, _ = evict(kept, opts.MaxSize)
+
+	removed := len(cacheData) - len(kept)
+	if removed > 0 {
+		if err := encode(cachePath(), kept); err != nil {
+			return 0, err
+		}
+	}
+	return removed, nil
+}
+
+// Verify reads every entry of the cache and checks that it can be used. Returns the number of entries which are
+// usable, and an error describing the first problem if any of them are not.
+func Verify() (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	usable, err := salvage()
+	return len(usable), err
+}
+
+// Salvage removes the entries which can't be read or used from the cache. The original file is kept with a ".corrupt"
+// suffix. Returns the number of entries which were kept, and the problem found in the cache, if any. Nothing is
+// changed if there was no problem.
+func Salvage() (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	usable, problem := salvage()
+	if problem == nil {
+		return len(usable), nil
+	}
+	b, err := os.ReadFile(cachePath())
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(cachePath()+".corrupt", b, 0644); err != nil {
+		return 0, fmt.Errorf("unable to back up the cache: %w", err)
+	}
+	if err := encode(cachePath(), usable); err != nil {
+		return 0, err
+	}
+	return len(usable), problem
+}
+
+// salvage returns the entries of the cache which can be read and used, and the first problem found.
+// Reading stops at the first entry which can't be decoded, since the rest of the file can't be decoded either.
+func salvage() ([]data, error) {
+	cacheData, problem := decode(cachePath())
+	if os.IsNotExist(problem) {
+		return nil, nil
+	}
+
+	var usable []data
+	for i, d := range cacheData {
+		if err := d.entry().validate(); err != nil {
+			if problem == nil {
+				problem = fmt.Errorf("entry %d: %w", i+1, err)
+			}
+			continue
+		}
+		usable = append(usable, d)
+	}
+	return usable, problem
+}
+
+// evict removes the least recently used entries from the given data until its encoded size is at most maxSize bytes.
+// Nothing is removed if maxSize is not positive. Returns the remaining entries and the number of removed entries.
+func evict(cacheData []data, maxSize int64) ([]data, int) {
+	if maxSize <= 0 {
+		return cacheData, 0
+	}
+	sizes := entrySizes(cacheData)
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	if total <= maxSize {
+		return cacheData, 0
+	}
+
+	order := make([]int, len(cacheData))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return cacheData[order[i]].lastUsed().Before(cacheData[order[j]].lastUsed())
+	})
+	removed := map[int]bool{}
+	for _, i := range order {
+		if total <= maxSize {
+			break
+		}
+		removed[i] = true
+		total -= sizes[i]
+	}
+
+	var kept []data
+	for i, d := range cacheData {
+		if !removed[i] {
+			kept = append(kept, d)
+		}
+	}
+	return kept, len(removed)
+}
+
+// lastUsed returns when the entry was last used, or when it was added if it was never used.
+func (d data) lastUsed() time.Time {
+	if d.Used.IsZero() {
+		return d.Updated
+	}
+	return d.Used
+}
+
+// entrySizes returns the size of each of the given entries in the cache file, in bytes.
+// The size of the file header and type information is included in the size of the first entry.
+func entrySizes(cacheData []data) []int64 {
+	var w countingWriter
+	enc := gob.NewEncoder(&w)
+	enc.Encode(formatHeader)
+
+	sizes := make([]int64, len(cacheData))
+	for i, d := range cacheData {
+		before := w.n
+		enc.Encode(d)
+		sizes[i] = w.n - before
+	}
+	return sizes
+}
+
+// countingWriter counts the bytes written to it and discards them.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"bytes"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"os"
+	"testing"
+	"time"
+)
+
+// testData returns cache data of the given image, last used at the given time.
+func testData(hash string, used time.Time) data {
+	return data{
+		Hash:     hash,
+		Service:  "google",
+		Language: "en",
+		Updated:  used.Add(-time.Hour),
+		Used:     used,
+		Blocks: []detect.TextBlock{{
+			Text:       "原文",
+			Translated: "Translation",
+			Vertices:   []*pb.Vertex{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+		}},
+	}
+}
+
+// hashes returns the image hashes of the given data.
+func hashes(cacheData []data) []string {
+	var h []string
+	for _, d := range cacheData {
+		h = append(h, d.Hash)
+	}
+	return h
+}
+
+func TestEvict(t *testing.T) {
+	now := time.Now()
+	cacheData := []data{
+		testData(testHash("a"), now),
+		testData(testHash("b"), now.Add(-3*time.Hour)),
+		testData(testHash("c"), now.Add(-2*time.Hour)),
+		// Never used, so it was last used when it was added.
+		testData(testHash("d"), time.Time{}),
+	}
+	cacheData[3].Updated = now.Add(-time.Hour)
+
+	sizes := entrySizes(cacheData)
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		want    []string
+	}{
+		{"no limit", 0, hashes(cacheData)},
+		{"negative limit", -1, hashes(cacheData)},
+		{"within limit", total, hashes(cacheData)},
+		{"least recently used", total - sizes[1], []string{testHash("a"), testHash("c"), testHash("d")}},
+		{"two least recently used", total - sizes[1] - 1, []string{testHash("a"), testHash("d")}},
+		{"unused by time added", total - sizes[1] - sizes[2] - 1, []string{testHash("a")}},
+		{"everything", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, removed := evict(append([]data(nil), cacheData...), tt.maxSize)
+			got := hashes(kept)
+			if len(got) != len(tt.want) || removed != len(cacheData)-len(tt.want) {
+				t.Fatalf("kept %d entries and removed %d, want %d kept", len(got), removed, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("kept %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddEvicts(t *testing.T) {
+	resetCache(t)
+	now := time.Now()
+	cacheData := []data{testData(testHash("a"), now.Add(-time.Hour)), testData(testHash("b"), now)}
+	if err := encode(cachePath(), cacheData); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(cachePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new entry has the same size as the others, so the least recently used entry is removed.
+	d := testData(testHash("c"), now)
+	if err := Add(Image{Hash: d.Hash}, d.Service, d.Language, "", d.Blocks, info.Size()); err != nil {
+		t.Fatal(err)
+	}
+	if got := hashes(readCacheFile(t)); len(got) != 2 || got[0] != testHash("b") || got[1] != testHash("c") {
+		t.Errorf("got entries %v, want the least recently used entry removed", got)
+	}
+}
+
+func TestSalvage(t *testing.T) {
+	resetCache(t)
+	now := time.Now()
+	cacheData := []data{testData(testHash("a"), now), testData(testHash("b"), now), testData(testHash("c"), now)}
+	// Readable, but can't be used.
+	cacheData[1].Blocks[0].Vertices = cacheData[1].Blocks[0].Vertices[:3]
+	if err := encode(cachePath(), cacheData); err != nil {
+		t.Fatal(err)
+	}
+	// Cut the file in the middle of the last entry.
+	sizes := entrySizes(cacheData)
+	original, err := os.ReadFile(cachePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := original[:int64(len(original))-sizes[2]/2]
+	if err := os.WriteFile(cachePath(), truncated, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Check(Image{Hash: testHash("a")}, "google", "en", "", -1); err == nil {
+		t.Error("corrupted cache was read without an error")
+	}
+	if n, err := Verify(); n != 1 || err == nil {
+		t.Errorf("Verify() = %d, %v, want 1 usable entry and an error", n, err)
+	}
+
+	n, err := Salvage()
+	if n != 1 || err == nil {
+		t.Errorf("Salvage() = %d, %v, want 1 kept entry and the problem", n, err)
+	}
+	if got := hashes(readCacheFile(t)); len(got) != 1 || got[0] != testHash("a") {
+		t.Errorf("got entries %v after salvaging, want the entry before the corrupted one", got)
+	}
+	backup, err := os.ReadFile(cachePath() + ".corrupt")
+	if err != nil || !bytes.Equal(backup, truncated) {
+		t.Errorf("corrupted cache was not backed up: %v", err)
+	}
+
+	// Nothing is changed once the cache is usable.
+	if n, err := Salvage(); n != 1 || err != nil {
+		t.Errorf("Salvage() = %d, %v after salvaging, want 1 entry and no problem", n, err)
+	}
+	if _, _, err := Check(Image{Hash: testHash("a")}, "google", "en", "", -1); err != nil {
+		t.Errorf("salvaged cache can't be read: %v", err)
+	}
+}
+
+func TestSalvageMissingCache(t *testing.T) {
+	resetCache(t)
+	if n, err := Salvage(); n != 0 || err != nil {
+		t.Errorf("Salvage() = %d, %v without a cache, want nothing to do", n, err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	resetCache(t)
+	now := time.Now()
+	cacheData := []data{
+		testData(testHash("a"), now),
+		testData(testHash("b"), now),
+		testData(testHash("c"), now.Add(-48*time.Hour)),
+		// Added before the time was recorded, so its age is unknown.
+		testData(testHash("d"), time.Time{}),
+	}
+	cacheData[3].Updated = time.Time{}
+	if err := encode(cachePath(), cacheData); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Prune(PruneOptions{Hashes: []string{testHash("b")}, OlderThan: 24 * time.Hour})
+	if err != nil || n != 2 {
+		t.Errorf("Prune() = %d, %v, want 2 removed entries", n, err)
+	}
+	if got := hashes(readCacheFile(t)); len(got) != 2 || got[0] != testHash("a") || got[1] != testHash("d") {
+		t.Errorf("got entries %v, want the entry of a and the entry of unknown age", got)
+	}
+}
+
+func TestMissingCache(t *testing.T) {
+	resetCache(t)
+	if stats, err := Inspect(); err != nil || stats.Entries != 0 || stats.Size != 0 || len(stats.Groups) != 0 {
+		t.Errorf("Inspect() = %+v, %v without a cache, want no entries", stats, err)
+	}
+	if n, err := Prune(PruneOptions{Hashes: []string{testHash("a")}, MaxSize: 1}); n != 0 || err != nil {
+		t.Errorf("Prune() = %d, %v without a cache, want nothing to do", n, err)
+	}
+	if _, err := os.Stat(cachePath()); !os.IsNotExist(err) {
+		t.Errorf("got cache file error %v, want the file not to be created", err)
+	}
+}
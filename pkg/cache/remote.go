@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNotFound is returned by Remote.Check if the image is not in the remote cache.
+var errNotFound = errors.New("not found in remote cache")
+
+// Remote is a client of a cache server (see Handler).
+type Remote struct {
+	URL    string // Base URL of the server, e.g. "http://cache.example.com:8080".
+	Token  string // Shared token of the server.
+	Client *http.Client
+}
+
+// remoteTimeout is the maximum time to wait for the cache server, after which the local cache is used.
+var remoteTimeout = 5 * time.Second
+
+// Check looks up the given image in the remote cache, like Check. Returns nil blocks if the image is not in the
+// remote cache, and an error if the server could not be reached or returned an error.
+func (rc Remote) Check(ctx context.Context, img Image, service, language, glossary string, maxDistance int) ([]detect.TextBlock, bool, error) {
+	q := url.Values{
+		"service":     {service},
+		"language":    {language},
+		"maxDistance": {strconv.Itoa(maxDistance)},
+	}
+	if glossary != "" {
+		q.Set("glossary", glossary)
+	}
+	if img.Width > 0 && img.Height > 0 {
+		q.Set("phash", fmt.Sprintf("%016x", img.PHash))
+		q.Set("width", strconv.Itoa(img.Width))
+		q.Set("height", strconv.Itoa(img.Height))
+	}
+
+	var res CheckResponse
+	err := rc.do(ctx, http.MethodGet, img.Hash+"?"+q.Encode(), nil, &res)
+	if errors.Is(err, errNotFound) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return fromBlocks(res.Blocks), res.TranslateOnly, nil
+}
+
+// Add adds a new entry to the remote cache, like Add.
+func (rc Remote) Add(ctx context.Context, img Image, service, language, glossary string, blocks []detect.TextBlock) error {
+	d := data{
+		Hash:     img.Hash,
+		Service:  service,
+		Language: language,
+		Glossary: glossary,
+		Blocks:   blocks,
+		Updated:  time.Now(),
+		PHash:    img.PHash,
+		Width:    img.Width,
+		Height:   img.Height,
+	}
+	body, err := json.Marshal(d.entry())
+	if err != nil {
+		return err
+	}
+	return rc.do(ctx, http.MethodPut, img.Hash, bytes.NewReader(body), nil)
+}
+
+// do sends a request for the entry at the given path to the server, and decodes the response into res if it is not nil.
+func (rc Remote) do(ctx context.Context, method, path string, body io.Reader, res interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, remoteTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(rc.URL, "/")+"/v1/entries/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+rc.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := rc.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errNotFound
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("cache server: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	case res != nil:
+		return json.NewDecoder(resp.Body).Decode(res)
+	}
+	return nil
+}
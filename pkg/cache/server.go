@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEntrySize is the maximum size of an entry sent to the cache server, in bytes.
+var maxEntrySize int64 = 16 << 20
+
+// CheckResponse is the response of the cache server to a lookup, the result of Check.
+type CheckResponse struct {
+	TranslateOnly bool    `json:"translateOnly"`
+	Blocks        []Block `json:"blocks"`
+}
+
+// Handler serves the local cache over HTTP, so it can be shared by a team:
+//
+//	GET /v1/entries/{sha256}?service=&language=&glossary=&phash=&width=&height=&maxDistance=
+//	    Looks up an image like Check. Responds with a CheckResponse, or 404 if it is not in cache.
+//	PUT /v1/entries/{sha256}
+//	    Adds the Entry in the request body like Put, replacing the entry of the same image, service, language and
+//	    glossary.
+//
+// Every request must have the header "Authorization: Bearer <token>", all requests are rejected if the token is blank.
+// If maxSize is positive, the least recently used entries are removed until the cache is at most maxSize bytes.
+func Handler(token string, maxSize int64) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/entries/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		img := Image{Hash: r.PathValue("hash")}
+		img.PHash, _ = strconv.ParseUint(q.Get("phash"), 16, 64)
+		img.Width, _ = strconv.Atoi(q.Get("width"))
+		img.Height, _ = strconv.Atoi(q.Get("height"))
+		maxDistance, err := strconv.Atoi(q.Get("maxDistance"))
+		if err != nil {
+			maxDistance = -1
+		}
+
+		blocks, translateOnly, err := Check(img, q.Get("service"), q.Get("language"), q.Get("glossary"), maxDistance)
+		if err != nil {
+			log.Errorf("Cache lookup failed: %v", err)
+			http.Error(w, "cache unavailable", http.StatusInternalServerError)
+			return
+		}
+		if blocks == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CheckResponse{TranslateOnly: translateOnly, Blocks: ToBlocks(blocks)})
+	})
+	mux.HandleFunc("PUT /v1/entries/{hash}", func(w http.ResponseWriter, r *http.Request) {
+		var e Entry
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEntrySize)).Decode(&e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := e.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if e.SHA256 != r.PathValue("hash") {
+			http.Error(w, "sha256 of the entry doesn't match the URL", http.StatusBadRequest)
+			return
+		}
+		if e.Updated.IsZero() {
+			e.Updated = time.Now()
+		}
+		if _, err := Put(e, maxSize); err != nil {
+			log.Errorf("Cache update failed: %v", err)
+			http.Error(w, "cache unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		auth, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !bearer || token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			log.Warningf("Unauthorized cache request from %v", r.RemoteAddr)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+		log.Infof("%s %s (%v)", r.Method, r.URL.Path, time.Since(start))
+	})
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testServer starts a cache server with the given token, and returns a client of it with the same token.
+func testServer(t *testing.T, token string) (*httptest.Server, Remote) {
+	t.Helper()
+	srv := httptest.NewServer(Handler(token, 0))
+	t.Cleanup(srv.Close)
+	return srv, Remote{URL: srv.URL + "/", Token: token, Client: srv.Client()}
+}
+
+// testBlocks returns a single block with the given translation.
+func testBlocks(translated string) []detect.TextBlock {
+	return []detect.TextBlock{{
+		Text:       "原文",
+		Translated: translated,
+		Vertices:   []*pb.Vertex{{X: 10, Y: 20}, {X: 110, Y: 20}, {X: 110, Y: 80}, {X: 10, Y: 80}},
+	}}
+}
+
+// put sends the given entry to the given URL of the server, and returns the status code.
+func put(t *testing.T, srv *httptest.Server, token, hash string, e Entry) int {
+	t.Helper()
+	body, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/v1/entries/"+hash, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServerToken(t *testing.T) {
+	resetCache(t)
+	srv, _ := testServer(t, "secret")
+
+	for name, auth := range map[string]string{
+		"missing":      "",
+		"wrong":        "Bearer wrong",
+		"not a bearer": "secret",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/entries/"+testHash("a")+"?service=google", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s token: got status %d, want 401", name, resp.StatusCode)
+		}
+	}
+
+	wrong := Remote{URL: srv.URL, Token: "wrong", Client: srv.Client()}
+	if _, _, err := wrong.Check(context.Background(), Image{Hash: testHash("a")}, "google", "en", "", -1); err == nil {
+		t.Error("Check() with a wrong token returned no error")
+	}
+
+	// A server without a token accepts nothing.
+	blank, _ := testServer(t, "")
+	if code := put(t, blank, "", testHash("a"), testEntry(testHash("a"), "google", "en", "Hello", time.Now(), false)); code != http.StatusUnauthorized {
+		t.Errorf("got status %d from a server without a token, want 401", code)
+	}
+}
+
+func TestServerCheck(t *testing.T) {
+	resetCache(t)
+	_, remote := testServer(t, "secret")
+	ctx := context.Background()
+	img := Image{Hash: testHash("a"), PHash: 0xff00ff00ff00ff00, Width: 1000, Height: 1500}
+
+	blocks, translateOnly, err := remote.Check(ctx, img, "google", "en", "", 4)
+	if blocks != nil || translateOnly || err != nil {
+		t.Errorf("Check() of an image which is not cached = %v, %v, %v, want nothing", blocks, translateOnly, err)
+	}
+
+	if err := remote.Add(ctx, img, "google", "en", "", testBlocks("Hello")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		img           Image
+		service       string
+		glossary      string
+		translateOnly bool
+	}{
+		{"hit", img, "google", "", false},
+		{"other service", img, "deepL", "", true},
+		{"other glossary", img, "google", "0123456789abcdef", true},
+		{"similar image", Image{Hash: testHash("b"), PHash: img.PHash, Width: 500, Height: 750}, "google", "", false},
+	}
+	for _, tt := range tests {
+		blocks, translateOnly, err := remote.Check(ctx, tt.img, tt.service, "en", tt.glossary, 4)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(blocks) != 1 || translateOnly != tt.translateOnly || blocks[0].Text != "原文" {
+			t.Errorf("%s: got blocks %v, translateOnly %v, want translateOnly %v", tt.name, blocks, translateOnly, tt.translateOnly)
+		}
+	}
+	if blocks, _, _ := remote.Check(ctx, img, "google", "en", "", -1); blocks[0].Translated != "Hello" {
+		t.Errorf("got translation %q, want the added translation", blocks[0].Translated)
+	}
+}
+
+func TestServerPut(t *testing.T) {
+	resetCache(t)
+	srv, remote := testServer(t, "secret")
+	ctx := context.Background()
+	hash := testHash("c")
+	translation := func() string {
+		t.Helper()
+		blocks, translateOnly, err := remote.Check(ctx, Image{Hash: hash}, "google", "en", "", -1)
+		if err != nil || translateOnly || len(blocks) != 1 {
+			t.Fatalf("Check() = %v, %v, %v, want the entry", blocks, translateOnly, err)
+		}
+		return blocks[0].Translated
+	}
+
+	if err := remote.Add(ctx, Image{Hash: hash}, "google", "en", "", testBlocks("first")); err != nil {
+		t.Fatal(err)
+	}
+	// An edited entry replaces a newer unedited one, like an import.
+	edited := testEntry(hash, "google", "en", "edited", time.Now().Add(-time.Hour), true)
+	if code := put(t, srv, "secret", hash, edited); code != http.StatusNoContent {
+		t.Fatalf("got status %d, want 204", code)
+	}
+	if got := translation(); got != "edited" {
+		t.Errorf("got translation %q, want the edited entry", got)
+	}
+	if err := remote.Add(ctx, Image{Hash: hash}, "google", "en", "", testBlocks("newer")); err != nil {
+		t.Fatal(err)
+	}
+	if got := translation(); got != "edited" {
+		t.Errorf("got translation %q, want the edited entry to be kept", got)
+	}
+	if entries := exportAll(t); len(entries) != 1 {
+		t.Errorf("got %d entries, want the entry to be replaced", len(entries))
+	}
+
+	if code := put(t, srv, "secret", testHash("d"), testEntry(hash, "google", "en", "Hello", time.Now(), false)); code != http.StatusBadRequest {
+		t.Errorf("got status %d for an entry of another image, want 400", code)
+	}
+	invalid := testEntry(hash, "google", "en", "Hello", time.Now(), false)
+	invalid.Blocks[0].Vertices = invalid.Blocks[0].Vertices[:2]
+	if code := put(t, srv, "secret", hash, invalid); code != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid entry, want 400", code)
+	}
+}
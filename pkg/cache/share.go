@@ -0,0 +1,268 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a line of the JSON-lines format used to share the cache, see the README of this package.
+type Entry struct {
+	SHA256   string    `json:"sha256"`
+	Service  string    `json:"service"`
+	Language string    `json:"language,omitempty"`
+	Glossary string    `json:"glossary,omitempty"` // Digest of the glossary used to translate.
+	Updated  time.Time `json:"updated"`
+	Edited   bool      `json:"edited,omitempty"`
+	PHash    string    `json:"phash,omitempty"` // Perceptual hash of the image, as 16 hexadecimal digits.
+	Width    int       `json:"width,omitempty"`
+	Height   int       `json:"height,omitempty"`
+	Blocks   []Block   `json:"blocks"`
+}
+
+// Block is a text block of an Entry. The vertices are the corners of the block in pixels of the full size image,
+// clockwise from the top left.
+type Block struct {
+	Text       string   `json:"text"`
+	Translated string   `json:"translated"`
+	Vertices   []Vertex `json:"vertices"`
+}
+
+// Vertex is a corner of a Block.
+type Vertex struct {
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
+}
+
+// ImportResult is the number of entries which were imported, and the number which were not.
+type ImportResult struct {
+	Added    int // New entries.
+	Replaced int // Entries which replaced an older or unedited entry in the cache.
+	Kept     int // Entries which were not imported since the entry in the cache is newer or edited.
+	Evicted  int // Least recently used entries which were removed to stay within the maximum size.
+}
+
+// Export writes the cache to w in the JSON-lines format. If hashes are given, only the entries of those images are
+// written. Returns the number of entries written.
+func Export(w io.Writer, hashes []string) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var include map[string]bool
+	if len(hashes) > 0 {
+		include = map[string]bool{}
+		for _, h := range hashes {
+			include[h] = true
+		}
+	}
+
+	cacheData, err := read()
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+	n := 0
+	for _, d := range cacheData {
+		if include != nil && !include[d.Hash] {
+			continue
+		}
+		if err := enc.Encode(d.entry()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Import merges the entries in the JSON-lines format read from r into the cache. If the cache already has an entry
+// for the same image, service and language, an edited entry is kept over an unedited one, and otherwise the newest
+// entry is kept. Nothing is imported if any line is invalid. If maxSize is positive, the least recently used entries
+// are removed until the cache is at most maxSize bytes.
+func Import(r io.Reader, maxSize int64) (ImportResult, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return ImportResult{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := e.validate(); err != nil {
+			return ImportResult{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return ImportResult{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var res ImportResult
+	cacheData, err := read()
+	if err != nil {
+		return res, err
+	}
+	for _, e := range entries {
+		cacheData = merge(cacheData, e.data(), &res)
+	}
+	if res.Added > 0 || res.Replaced > 0 {
+		cacheData, res.Evicted = evict(cacheData, maxSize)
+		if err := write(cacheData); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// Put adds the given entry to the cache, or replaces the entry for the same image, service and language by the same
+// rules as Import. If maxSize is positive, the least recently used entries are removed until the cache is at most
+// maxSize bytes.
+func Put(e Entry, maxSize int64) (ImportResult, error) {
+	if err := e.validate(); err != nil {
+		return ImportResult{}, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var res ImportResult
+	cacheData, err := read()
+	if err != nil {
+		return res, err
+	}
+	cacheData = merge(cacheData, e.data(), &res)
+	if res.Kept > 0 {
+		return res, nil
+	}
+	cacheData, res.Evicted = evict(cacheData, maxSize)
+	return res, write(cacheData)
+}
+
+// merge adds the imported entry to the given data, or replaces the existing entry for the same image, service and
+// language if the imported one is newer. The outcome is counted in res.
+func merge(cacheData []data, imported data, res *ImportResult) []data {
+	i := find(cacheData, imported)
+	switch {
+	case i < 0:
+		res.Added++
+		return append(cacheData, imported)
+	case newer(imported, cacheData[i]):
+		res.Replaced++
+		cacheData[i] = imported
+	default:
+		res.Kept++
+	}
+	return cacheData
+}
+
+// find returns the index of the entry in the given data with the same image, service, language and glossary as the
+// given entry, or -1 if there is none.
+func find(cacheData []data, d data) int {
+	for i, existing := range cacheData {
+		if existing.Hash == d.Hash && existing.Service == d.Service && existing.Language == d.Language &&
+			existing.Glossary == d.Glossary {
+			return i
+		}
+	}
+	return -1
+}
+
+// newer returns if the imported entry should replace the existing entry: edited entries are kept over unedited ones,
+// otherwise the newest entry is kept.
+func newer(imported, existing data) bool {
+	if imported.Edited != existing.Edited {
+		return imported.Edited
+	}
+	return imported.Updated.After(existing.Updated)
+}
+
+// validate returns an error if the entry can't be added to the cache.
+func (e Entry) validate() error {
+	if len(e.SHA256) != 64 {
+		return fmt.Errorf("invalid sha256 %q", e.SHA256)
+	}
+	if e.Service == "" {
+		return fmt.Errorf("missing service for sha256 %v", e.SHA256)
+	}
+	if e.PHash != "" {
+		if _, err := strconv.ParseUint(e.PHash, 16, 64); err != nil || e.Width <= 0 || e.Height <= 0 {
+			return fmt.Errorf("invalid phash %q or dimensions for sha256 %v", e.PHash, e.SHA256)
+		}
+	}
+	for i, b := range e.Blocks {
+		if len(b.Vertices) != 4 {
+			return fmt.Errorf("block %d of sha256 %v has %d vertices, expected 4", i, e.SHA256, len(b.Vertices))
+		}
+	}
+	return nil
+}
+
+// entry converts the cache data to an Entry.
+func (d data) entry() Entry {
+	e := Entry{
+		SHA256:   d.Hash,
+		Service:  d.Service,
+		Language: d.Language,
+		Glossary: d.Glossary,
+		Updated:  d.Updated,
+		Edited:   d.Edited,
+		Blocks:   ToBlocks(d.Blocks),
+	}
+	if d.Width > 0 && d.Height > 0 {
+		e.PHash, e.Width, e.Height = fmt.Sprintf("%016x", d.PHash), d.Width, d.Height
+	}
+	return e
+}
+
+// data converts the Entry to cache data.
+func (e Entry) data() data {
+	d := data{
+		Hash:     e.SHA256,
+		Service:  e.Service,
+		Language: e.Language,
+		Glossary: e.Glossary,
+		Updated:  e.Updated,
+		Edited:   e.Edited,
+		Blocks:   fromBlocks(e.Blocks),
+	}
+	if e.PHash != "" {
+		d.PHash, _ = strconv.ParseUint(e.PHash, 16, 64)
+		d.Width, d.Height = e.Width, e.Height
+	}
+	return d
+}
+
+// ToBlocks converts text blocks to the blocks of an Entry, e.g. to send them as JSON.
+func ToBlocks(blocks []detect.TextBlock) []Block {
+	converted := make([]Block, len(blocks))
+	for i, b := range blocks {
+		converted[i] = Block{Text: b.Text, Translated: b.Translated}
+		for _, v := range b.Vertices {
+			converted[i].Vertices = append(converted[i].Vertices, Vertex{X: v.X, Y: v.Y})
+		}
+	}
+	return converted
+}
+
+// fromBlocks converts the blocks of an Entry to text blocks.
+func fromBlocks(blocks []Block) []detect.TextBlock {
+	converted := make([]detect.TextBlock, len(blocks))
+	for i, b := range blocks {
+		converted[i] = detect.TextBlock{Text: b.Text, Translated: b.Translated, Color: detect.BorderColor(i)}
+		for _, v := range b.Vertices {
+			converted[i].Vertices = append(converted[i].Vertices, &pb.Vertex{X: v.X, Y: v.Y})
+		}
+	}
+	return converted
+}
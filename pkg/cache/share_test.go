@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testHash returns a sha256 hash made of the given character.
+func testHash(c string) string {
+	return strings.Repeat(c, 64)
+}
+
+// testEntry returns an entry of the given image with a single block with the given translation.
+func testEntry(hash, service, language, translated string, updated time.Time, edited bool) Entry {
+	return Entry{
+		SHA256:   hash,
+		Service:  service,
+		Language: language,
+		Updated:  updated.UTC(),
+		Edited:   edited,
+		Blocks: []Block{{
+			Text:       "原文",
+			Translated: translated,
+			Vertices:   []Vertex{{10, 20}, {110, 20}, {110, 80}, {10, 80}},
+		}},
+	}
+}
+
+// jsonLines returns the given entries in the JSON-lines format.
+func jsonLines(t *testing.T, entries ...Entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+// exportAll returns all entries of the cache.
+func exportAll(t *testing.T) []Entry {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := Export(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	var entries []Entry
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	resetCache(t)
+	now := time.Now().Truncate(time.Second)
+	a := testEntry(testHash("a"), "google", "en", "Hello", now, false)
+	a.PHash, a.Width, a.Height = "00ff00ff00ff00ff", 800, 1200
+	b := testEntry(testHash("b"), "deepL", "EN-US", "Goodbye", now.Add(-time.Hour), true)
+	if _, err := Import(jsonLines(t, a, b), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var exported bytes.Buffer
+	n, err := Export(&exported, nil)
+	if err != nil || n != 2 {
+		t.Fatalf("exported %d entries, error %v, want 2", n, err)
+	}
+
+	// Import the export into an empty cache, the entries are the same.
+	resetCache(t)
+	res, err := Import(bytes.NewReader(exported.Bytes()), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (ImportResult{Added: 2}) {
+		t.Errorf("got %+v, want 2 added entries", res)
+	}
+	if got := exportAll(t); !reflect.DeepEqual(got, []Entry{a, b}) {
+		t.Errorf("got entries %+v, want %+v", got, []Entry{a, b})
+	}
+
+	var filtered bytes.Buffer
+	if n, err := Export(&filtered, []string{testHash("b")}); err != nil || n != 1 {
+		t.Errorf("exported %d entries of one image, error %v, want 1", n, err)
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now()
+	hash := testHash("c")
+
+	tests := []struct {
+		name     string
+		existing Entry
+		imported Entry
+		want     ImportResult
+		wantText string // Translation in the cache after the import.
+	}{
+		{
+			name:     "edited beats a newer unedited entry",
+			existing: testEntry(hash, "google", "en", "cached", newer, false),
+			imported: testEntry(hash, "google", "en", "edited", older, true),
+			want:     ImportResult{Replaced: 1},
+			wantText: "edited",
+		},
+		{
+			name:     "edited entry is kept over a newer unedited entry",
+			existing: testEntry(hash, "google", "en", "edited", older, true),
+			imported: testEntry(hash, "google", "en", "imported", newer, false),
+			want:     ImportResult{Kept: 1},
+			wantText: "edited",
+		},
+		{
+			name:     "newest wins",
+			existing: testEntry(hash, "google", "en", "cached", older, false),
+			imported: testEntry(hash, "google", "en", "imported", newer, false),
+			want:     ImportResult{Replaced: 1},
+			wantText: "imported",
+		},
+		{
+			name:     "older is kept",
+			existing: testEntry(hash, "google", "en", "cached", newer, false),
+			imported: testEntry(hash, "google", "en", "imported", older, false),
+			want:     ImportResult{Kept: 1},
+			wantText: "cached",
+		},
+		{
+			name:     "newest of two edited entries wins",
+			existing: testEntry(hash, "google", "en", "cached", older, true),
+			imported: testEntry(hash, "google", "en", "imported", newer, true),
+			want:     ImportResult{Replaced: 1},
+			wantText: "imported",
+		},
+		{
+			name:     "blank languages match",
+			existing: testEntry(hash, "google", "", "cached", older, false),
+			imported: testEntry(hash, "google", "", "imported", newer, false),
+			want:     ImportResult{Replaced: 1},
+			wantText: "imported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetCache(t)
+			if _, err := Import(jsonLines(t, tt.existing), 0); err != nil {
+				t.Fatal(err)
+			}
+			res, err := Import(jsonLines(t, tt.imported), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.want {
+				t.Errorf("got %+v, want %+v", res, tt.want)
+			}
+			entries := exportAll(t)
+			if len(entries) != 1 || entries[0].Blocks[0].Translated != tt.wantText {
+				t.Errorf("got entries %+v, want one translated %q", entries, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestImportOtherTranslation(t *testing.T) {
+	resetCache(t)
+	now := time.Now()
+	hash := testHash("d")
+	if _, err := Import(jsonLines(t, testEntry(hash, "google", "en", "Hello", now, false)), 0); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Import(jsonLines(t,
+		testEntry(hash, "deepL", "EN-US", "Hello", now, false),
+		testEntry(hash, "google", "de", "Hallo", now, false),
+	), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != (ImportResult{Added: 2}) {
+		t.Errorf("got %+v, want entries of other services and languages to be added", res)
+	}
+}
+
+func TestImportBlankLanguageIsTranslatedAgain(t *testing.T) {
+	resetCache(t)
+	// Entries without a language were added before it was recorded, so only their text can be used.
+	e := testEntry(testHash("e"), "google", "", "Hello", time.Now(), false)
+	if _, err := Import(jsonLines(t, e), 0); err != nil {
+		t.Fatal(err)
+	}
+	blocks, translateOnly, err := Check(Image{Hash: e.SHA256}, "google", "en", "", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || !translateOnly || blocks[0].Text != "原文" {
+		t.Errorf("got blocks %+v, translateOnly %v, want the text of the imported entry to be translated", blocks, translateOnly)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	resetCache(t)
+	valid := testEntry(testHash("f"), "google", "en", "Hello", time.Now(), false)
+	invalid := testEntry("abc", "google", "en", "Hello", time.Now(), false)
+	missingVertex := testEntry(testHash("g"), "google", "en", "Hello", time.Now(), false)
+	missingVertex.Blocks[0].Vertices = missingVertex.Blocks[0].Vertices[:3]
+
+	for name, lines := range map[string]*bytes.Buffer{
+		"sha256":   jsonLines(t, valid, invalid),
+		"vertices": jsonLines(t, valid, missingVertex),
+		"json":     bytes.NewBufferString("{\n"),
+	} {
+		if _, err := Import(lines, 0); err == nil {
+			t.Errorf("invalid %s was imported", name)
+		}
+	}
+	if entries := exportAll(t); len(entries) != 0 {
+		t.Errorf("got %d entries, nothing should be imported if any line is invalid", len(entries))
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Cache is the cache section of mtl-config.yml.
+type Cache struct {
+	// MaxSize is the maximum size of the cache file, such as "500MB". When it is exceeded, the least recently used
+	// entries are removed. Blank for no limit.
+	MaxSize string `yaml:"maxSize,omitempty" json:"maxSize,omitempty"`
+	// Perceptual reuses the text of cached images which look the same as a new image, e.g. the same page in another
+	// format or size, instead of only the text of identical image files.
+	Perceptual bool `yaml:"perceptual,omitempty" json:"perceptual,omitempty"`
+	// Similarity is the maximum number of bits (out of 64) in which the perceptual hashes of images which look the same
+	// may differ. Blank uses the default.
+	Similarity int `yaml:"similarity,omitempty" json:"similarity,omitempty"`
+	// Server is the URL of a cache server shared by a team (manga-translator serve-cache), which is used before the
+	// APIs. Blank to only use the local cache.
+	Server string `yaml:"server,omitempty" json:"server,omitempty"`
+	// Token is the shared token of the cache server.
+	Token string `yaml:"token,omitempty" json:"token,omitempty"`
+}
+
+// defaultSimilarity is the maximum distance between the perceptual hashes of images which look the same, unless
+// configured otherwise.
+var defaultSimilarity = 4
+
+// MaxDistance returns the maximum distance between the perceptual hashes of images which look the same, or -1 if
+// perceptual matching is disabled.
+func (c Cache) MaxDistance() int {
+	switch {
+	case !c.Perceptual:
+		return -1
+	case c.Similarity <= 0:
+		return defaultSimilarity
+	default:
+		return c.Similarity
+	}
+}
+
+// sizeUnits are the units of sizes, in bytes.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// MaxSizeBytes returns the maximum size of the cache file in bytes, or 0 if there is no limit or it is invalid.
+func (c Cache) MaxSizeBytes() int64 {
+	n, err := ParseSize(c.MaxSize)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ParseSize parses a size in bytes with an optional unit: B, KB, MB or GB (powers of 1024), e.g. "500MB".
+// Returns 0 for a blank size.
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// FormatSize formats the given number of bytes with the largest unit which fits, e.g. "1.5 MB".
+func FormatSize(n int64) string {
+	for _, u := range sizeUnits {
+		if n >= u.bytes && u.bytes > 1 {
+			return fmt.Sprintf("%.1f %s", float64(n)/float64(u.bytes), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", n)
+}
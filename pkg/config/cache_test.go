@@ -0,0 +1,73 @@
+package config
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		invalid bool
+	}{
+		{in: "", want: 0},
+		{in: "  ", want: 0},
+		{in: "1024", want: 1024},
+		{in: "10B", want: 10},
+		{in: "2KB", want: 2 << 10},
+		{in: "500MB", want: 500 << 20},
+		{in: "500mb", want: 500 << 20},
+		{in: " 2 GB ", want: 2 << 30},
+		{in: "1.5GB", want: 3 << 29},
+		{in: "0", want: 0},
+		{in: "-1MB", invalid: true},
+		{in: "lots", invalid: true},
+		{in: "5TB", invalid: true},
+		{in: "MB", invalid: true},
+		{in: "infGB", invalid: true},
+		{in: "NaN", invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("ParseSize(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for _, tt := range []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{500 << 20, "500.0 MB"},
+		{3 << 29, "1.5 GB"},
+	} {
+		if got := FormatSize(tt.in); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaxDistance(t *testing.T) {
+	for _, tt := range []struct {
+		cache Cache
+		want  int
+	}{
+		{Cache{}, -1},
+		{Cache{Similarity: 10}, -1},
+		{Cache{Perceptual: true}, defaultSimilarity},
+		{Cache{Perceptual: true, Similarity: 10}, 10},
+	} {
+		if got := tt.cache.MaxDistance(); got != tt.want {
+			t.Errorf("%+v: got %d, want %d", tt.cache, got, tt.want)
+		}
+	}
+}
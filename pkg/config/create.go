@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"github.com/inancgumus/screen"
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"golang.org/x/text/language"
 	"google.golang.org/api/option"
 	"gopkg.in/yaml.v2"
@@ -20,22 +19,40 @@ import (
 	"strings"
 )
 
-// File is the mtl/mtl-config.yml structure.
+// File is the mtl-config.yml structure.
 type File struct {
-	CloudVision struct {
-		CredentialsPath string `yaml:"credentialsPath"`
-	} `yaml:"cloudVision"`
-	Translation struct {
-		SelectedService string `yaml:"selectedService"`
-		SourceLanguage  string `yaml:"sourceLanguage,omitempty"`
-		TargetLanguage  string `yaml:"targetLanguage"`
-		Google          struct {
-			APIKey string `yaml:"apiKey,omitempty"`
-		} `yaml:"google,omitempty"`
-		DeepL struct {
-			APIKey string `yaml:"apiKey,omitempty"`
-		} `yaml:"deepL,omitempty"`
-	} `yaml:"translation"`
+	Version     int         `yaml:"version" json:"version,omitempty"` // Format version of the file, see CurrentVersion.
+	CloudVision CloudVision `yaml:"cloudVision" json:"cloudVision"`
+	Translation Translation `yaml:"translation" json:"translation"`
+	Timeouts    Timeouts    `yaml:"timeouts,omitempty" json:"timeouts,omitempty"`
+	Preload     Preload     `yaml:"preload,omitempty" json:"preload,omitempty"`
+	Cache       Cache       `yaml:"cache,omitempty" json:"cache,omitempty"`
+	// Profile is the name of the profile used when none is given with the --profile flag.
+	Profile  string             `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+
+	// Settings of the open series, which are only set from its .mtl-series.yml (see Series) and never saved.
+	Series           string            `yaml:"-" json:"-" mapstructure:"-"` // Path of the series file in use.
+	ReadingDirection string            `yaml:"-" json:"-" mapstructure:"-"` // "rtl" (default) or "ltr".
+	Glossary         map[string]string `yaml:"-" json:"-" mapstructure:"-"` // Terms which are replaced before translating.
+}
+
+// CloudVision is the cloudVision section of mtl-config.yml.
+type CloudVision struct {
+	CredentialsPath string `yaml:"credentialsPath,omitempty" json:"credentialsPath,omitempty"`
+}
+
+// Translation is the translation section of mtl-config.yml.
+type Translation struct {
+	SelectedService string `yaml:"selectedService,omitempty" json:"selectedService,omitempty"`
+	SourceLanguage  string `yaml:"sourceLanguage,omitempty" json:"sourceLanguage,omitempty"`
+	TargetLanguage  string `yaml:"targetLanguage,omitempty" json:"targetLanguage,omitempty"`
+	Google          struct {
+		APIKey string `yaml:"apiKey,omitempty" json:"apiKey,omitempty"`
+	} `yaml:"google,omitempty" json:"google,omitempty"`
+	DeepL struct {
+		APIKey string `yaml:"apiKey,omitempty" json:"apiKey,omitempty"`
+	} `yaml:"deepL,omitempty" json:"deepL,omitempty"`
 }
 
 // deepLLanguage is the structure of language objects returned from the language list API.
@@ -78,10 +95,7 @@ func Create(modify bool) {
 			defer Create(false)
 			return
 		}
-		err := viper.Unmarshal(&newConfig)
-		if err != nil {
-			log.Fatalf("Unable to unmarshal existing config: %v", err)
-		}
+		Load(Path(), &newConfig)
 	}
 
 	// Google Cloud Vision API Key.
@@ -140,7 +154,9 @@ func Create(modify bool) {
 		setupTargetLanguage(&newConfig)
 	}
 
-	SaveConfig(newConfig)
+	if err := SaveConfig(newConfig); err != nil {
+		log.Fatal(err)
+	}
 	fmt.Println(`Config setup complete! Run the "manga-translator-setup" application again if you want to modify it.`)
 	fmt.Println("Press 'Enter' to exit.")
 	bufio.NewReader(os.Stdin).ReadBytes('\n')
@@ -270,11 +286,7 @@ func setupTargetLanguage(config *File) {
 			setupTargetLanguage(config)
 			return
 		} else if targetLang == "" {
-			if config.Translation.SelectedService == "google" {
-				targetLang = "en"
-			} else {
-				targetLang = "EN-US"
-			}
+			targetLang = DefaultTargetLanguage(config.Translation.SelectedService)
 		}
 		screen.Clear()
 		screen.MoveTopLeft()
@@ -283,6 +295,14 @@ func setupTargetLanguage(config *File) {
 	config.Translation.TargetLanguage = targetLang
 }
 
+// DefaultTargetLanguage returns the target language used when none is given for the given translation service.
+func DefaultTargetLanguage(service string) string {
+	if service == "google" {
+		return "en"
+	}
+	return "EN-US"
+}
+
 // isSupportedLanguage returns if the given ISO-639-1 language code is contained in the given slice of languages.
 func isSupportedLanguage(languageList []languageObj, languageCode string) bool {
 	for _, i := range languageList {
@@ -309,19 +329,23 @@ func getSupportedLanguages(config *File, languageType string) (languageList []la
 
 		var client *translate.Client
 		if config.Translation.Google.APIKey == "" {
-			err = os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", config.CloudVision.CredentialsPath)
+			err = SetCredentials(*config)
 			if err != nil {
 				log.Fatalf("Unable set GOOGLE_APPLICATION_CREDENTIALS: %v", err)
 			}
 			client, err = translate.NewClient(ctx)
 			if err != nil {
-				log.Fatalf("NewClient: %v", err)
+				log.Errorf("NewClient: %v", err)
+				fmt.Printf("Error: NewClient: %v", err)
+				return
 			}
 		} else {
 			apiKeyOption := option.WithAPIKey(config.Translation.Google.APIKey)
 			client, err = translate.NewClient(ctx, apiKeyOption)
 			if err != nil {
-				log.Fatalf("translate.NewClient: %v", err)
+				log.Errorf("translate.NewClient: %v", err)
+				fmt.Printf("Error: translate.NewClient: %v", err)
+				return
 			}
 		}
 		defer client.Close()
@@ -403,16 +427,17 @@ func modifyConfirmation(msg string) bool {
 	return false
 }
 
-// SaveConfig saves the given ConfigFile object in "mtl/mtl-config.yml".
-func SaveConfig(cfg File) {
+// SaveConfig saves the given ConfigFile object in "mtl-config.yml" in the config directory.
+func SaveConfig(cfg File) error {
+	cfg.Version = CurrentVersion
 	d, err := yaml.Marshal(&cfg)
 	if err != nil {
-		log.Fatalf("error: %v", err)
+		return err
 	}
 
 	configPath := filepath.Join(Path(), "mtl-config.yml")
-	err = ioutil.WriteFile(configPath, d, 0644)
-	if err != nil {
-		log.Fatal(err)
+	if err := ioutil.WriteFile(configPath, d, 0644); err != nil {
+		return fmt.Errorf("unable to save the config: %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables which override the config fields.
+var envPrefix = "MTL"
+
+// ApplyEnv overrides the fields of the given config with the values of their environment variables.
+//
+// The variable of a field is its path in mtl-config.yml in upper case, with the parts separated by underscores
+// and prefixed with MTL_, e.g. MTL_TRANSLATION_DEEPL_APIKEY for translation.deepL.apiKey.
+// If the variable is not set but the same variable with a _FILE suffix is, the value is read from the file it
+// points to instead (e.g. MTL_TRANSLATION_DEEPL_APIKEY_FILE=/run/secrets/deepl).
+func ApplyEnv(cfg *File) error {
+	return applyEnv(reflect.ValueOf(cfg).Elem(), envPrefix)
+}
+
+// applyEnv sets the string, int and bool fields of the given struct value from their environment variables.
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + "_" + strings.ToUpper(name)
+		field := v.Field(i)
+
+		switch field.Kind() {
+		case reflect.Struct:
+			if err := applyEnv(field, key); err != nil {
+				return err
+			}
+		case reflect.String:
+			value, ok, err := lookupEnv(key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				log.Debugf("Using %v from the environment", key)
+				field.SetString(value)
+			}
+		case reflect.Int:
+			value, ok, err := lookupEnv(key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("%v: %q is not a whole number", key, value)
+				}
+				log.Debugf("Using %v from the environment", key)
+				field.SetInt(int64(n))
+			}
+		case reflect.Bool:
+			value, ok, err := lookupEnv(key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				b, err := strconv.ParseBool(value)
+				if err != nil {
+					return fmt.Errorf("%v: %q is not true or false", key, value)
+				}
+				log.Debugf("Using %v from the environment", key)
+				field.SetBool(b)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupEnv returns the value of the given environment variable, or the contents of the file
+// given by the variable with a _FILE suffix.
+func lookupEnv(key string) (value string, ok bool, err error) {
+	if value, ok := os.LookupEnv(key); ok {
+		if _, ok := os.LookupEnv(key + "_FILE"); ok {
+			log.Warningf("Both %v and %v_FILE are set, using %v", key, key, key)
+		}
+		return value, true, nil
+	}
+
+	path, ok := os.LookupEnv(key + "_FILE")
+	if !ok {
+		return "", false, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%v_FILE: %w", key, err)
+	}
+	return strings.TrimSpace(string(data)), true, nil
+}
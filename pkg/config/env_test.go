@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    func(File) bool
+		invalid bool
+	}{
+		{
+			name: "string",
+			env:  map[string]string{"MTL_TRANSLATION_TARGETLANGUAGE": "de"},
+			want: func(f File) bool { return f.Translation.TargetLanguage == "de" },
+		},
+		{
+			name: "nested string",
+			env:  map[string]string{"MTL_TRANSLATION_DEEPL_APIKEY": "key"},
+			want: func(f File) bool { return f.Translation.DeepL.APIKey == "key" },
+		},
+		{
+			name: "empty string",
+			env:  map[string]string{"MTL_TRANSLATION_SOURCELANGUAGE": ""},
+			want: func(f File) bool { return f.Translation.SourceLanguage == "" },
+		},
+		{
+			name: "int",
+			env:  map[string]string{"MTL_CACHE_SIMILARITY": "8"},
+			want: func(f File) bool { return f.Cache.Similarity == 8 },
+		},
+		{
+			name: "bool",
+			env:  map[string]string{"MTL_CACHE_PERCEPTUAL": "true"},
+			want: func(f File) bool { return f.Cache.Perceptual },
+		},
+		{
+			name: "file",
+			env:  map[string]string{"MTL_TRANSLATION_DEEPL_APIKEY_FILE": secret},
+			want: func(f File) bool { return f.Translation.DeepL.APIKey == "from-file" },
+		},
+		{
+			name: "variable and file",
+			env:  map[string]string{"MTL_TRANSLATION_DEEPL_APIKEY": "key", "MTL_TRANSLATION_DEEPL_APIKEY_FILE": secret},
+			want: func(f File) bool { return f.Translation.DeepL.APIKey == "key" },
+		},
+		{
+			name: "unset",
+			env:  map[string]string{},
+			want: func(f File) bool { return f.Translation.TargetLanguage == "en" && f.Cache.Similarity == 4 },
+		},
+		{name: "invalid int", env: map[string]string{"MTL_CACHE_SIMILARITY": "four"}, invalid: true},
+		{name: "invalid bool", env: map[string]string{"MTL_CACHE_PERCEPTUAL": "yes please"}, invalid: true},
+		{name: "missing file", env: map[string]string{"MTL_TRANSLATION_DEEPL_APIKEY_FILE": filepath.Join(dir, "missing")}, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg := File{}
+			cfg.Translation.TargetLanguage = "en"
+			cfg.Translation.SourceLanguage = "ja"
+			cfg.Cache.Similarity = 4
+
+			err := ApplyEnv(&cfg)
+			if tt.invalid {
+				if err == nil {
+					t.Error("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.want(cfg) {
+				t.Errorf("got config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestResolveEnvPrecedence(t *testing.T) {
+	file := File{Profiles: map[string]Profile{"manhwa": {Translation: Translation{SourceLanguage: "ko", TargetLanguage: "fr"}}}}
+	file.Translation.TargetLanguage = "en"
+	series := &Series{Profile: "manhwa"}
+	t.Setenv("MTL_TRANSLATION_TARGETLANGUAGE", "de")
+
+	cfg, err := Resolve(file, "", series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The environment overrides the profile, which overrides the file.
+	if cfg.Translation.TargetLanguage != "de" || cfg.Translation.SourceLanguage != "ko" {
+		t.Errorf("got translation %+v, want the target language of the environment and the source of the profile", cfg.Translation)
+	}
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// CurrentVersion is the version of the mtl-config.yml format written by this version of manga-translator.
+// It must be increased, and a migration added, whenever the format changes in a way older files don't match.
+const CurrentVersion = 1
+
+// migration upgrades a config document from the previous version to version "to". The document is the top-level
+// mapping of the file, it is edited in place so the comments and order of the fields are kept.
+type migration struct {
+	to          int
+	description string
+	migrate     func(doc *yaml.Node)
+}
+
+// migrations are the upgrades of the config format, in order. Files without a version are version 0.
+var migrations = []migration{
+	{
+		to:          1,
+		description: "set translation.targetLanguage, which was always English before it could be configured (<=1.2.0)",
+		migrate: func(doc *yaml.Node) {
+			translation := mappingValue(doc, "translation")
+			if translation == nil || translation.Kind != yaml.MappingNode {
+				return
+			}
+			if target := mappingValue(translation, "targetLanguage"); target == nil || target.Value == "" {
+				var service string
+				if s := mappingValue(translation, "selectedService"); s != nil {
+					service = s.Value
+				}
+				setMappingValue(translation, "targetLanguage", "!!str", DefaultTargetLanguage(service), false)
+			}
+		},
+	},
+}
+
+// Migrate upgrades the mtl-config.yml in the given directory to the current version. The original file is kept
+// as mtl-config.yml.v<version>.bak. Files from a newer version of manga-translator are left unchanged.
+func Migrate(configPath string) error {
+	path := filepath.Join(configPath, "mtl-config.yml")
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		// The syntax error is reported when the config is loaded and checked.
+		return nil
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	doc := root.Content[0]
+
+	var version int
+	if v := mappingValue(doc, "version"); v != nil {
+		// Anything other than a number is treated as version 0.
+		v.Decode(&version)
+	}
+	if version > CurrentVersion {
+		log.Warningf("Config version %d is newer than the supported version %d, some fields may be ignored", version, CurrentVersion)
+		return nil
+	} else if version == CurrentVersion {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := ioutil.WriteFile(backup, data, 0644); err != nil {
+		return fmt.Errorf("unable to back up the config before migrating it: %w", err)
+	}
+	for _, m := range migrations {
+		if m.to <= version {
+			continue
+		}
+		log.Infof("Migrating config to version %d: %s", m.to, m.description)
+		m.migrate(doc)
+	}
+	setMappingValue(doc, "version", "!!int", strconv.Itoa(CurrentVersion), true)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	log.Infof("Config migrated from version %d to %d, the original was saved as %v", version, CurrentVersion, backup)
+	return ioutil.WriteFile(path, out.Bytes(), 0644)
+}
+
+// mappingValue returns the value of the given key in the given mapping node, or nil if it has no such key.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the given key of the given mapping node to a scalar with the given tag and value. A new key is
+// added at the start of the mapping if first is true, otherwise at the end.
+func setMappingValue(m *yaml.Node, key, tag, value string, first bool) {
+	if v := mappingValue(m, key); v != nil {
+		*v = yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value, LineComment: v.LineComment}
+		return
+	}
+	pair := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		{Kind: yaml.ScalarNode, Tag: tag, Value: value},
+	}
+	if first {
+		if len(m.Content) > 0 {
+			// Keep the comment at the top of the mapping above it.
+			pair[0].HeadComment, m.Content[0].HeadComment = m.Content[0].HeadComment, ""
+		}
+		m.Content = append(pair, m.Content...)
+	} else {
+		m.Content = append(m.Content, pair...)
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mtl-config.yml")
+	original := `# My config
+cloudVision:
+  credentialsPath: /keys/vision.json # Service account key
+translation:
+  selectedService: deepL
+  # Keys
+  deepL:
+    apiKey: abcdef123456
+preload:
+  workers: 2
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Comments and the order of the fields are kept.
+	want := `# My config
+version: 1
+cloudVision:
+  credentialsPath: /keys/vision.json # Service account key
+translation:
+  selectedService: deepL
+  # Keys
+  deepL:
+    apiKey: abcdef123456
+  targetLanguage: EN-US
+preload:
+  workers: 2
+`
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if backup, err := os.ReadFile(path + ".v0.bak"); err != nil || string(backup) != original {
+		t.Errorf("got backup %q, %v, want the original file", backup, err)
+	}
+
+	// Files of the current version are left unchanged.
+	if err := Migrate(dir); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != want {
+		t.Errorf("migrated file was changed again:\n%s", again)
+	}
+}
+
+func TestMigrateVersions(t *testing.T) {
+	for name, content := range map[string]string{
+		"newer":         "version: 2\ntranslation:\n  selectedService: google\n",
+		"invalid":       "translation: [\n",
+		"not a mapping": "- translation\n",
+	} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "mtl-config.yml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Migrate(dir); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if got, _ := os.ReadFile(path); string(got) != content {
+			t.Errorf("%s: file was changed to %q", name, got)
+		}
+		if _, err := os.Stat(path + ".v0.bak"); !os.IsNotExist(err) {
+			t.Errorf("%s: got backup error %v, want no backup", name, err)
+		}
+	}
+
+	if err := Migrate(t.TempDir()); err != nil {
+		t.Errorf("Migrate() without a config file: %v", err)
+	}
+}
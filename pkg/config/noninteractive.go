@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// LoadJSON reads a config in JSON format from the given reader and merges it into the given config.
+// The JSON object has the same structure as mtl-config.yml, fields which are omitted are left unchanged.
+func LoadJSON(r io.Reader, cfg *File) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("invalid JSON config: %w", err)
+	}
+	return nil
+}
+
+// ValidateLanguages checks that the source and target languages of the given config are supported by its
+// selected translation service. The supported languages are fetched from the service, so the config must
+// already have valid credentials.
+func ValidateLanguages(cfg *File) []FieldError {
+	var errs []FieldError
+	check := func(path, languageType, code string) {
+		supportedLangs := getSupportedLanguages(cfg, languageType)
+		if len(supportedLangs) == 0 {
+			errs = append(errs, FieldError{path, fmt.Sprintf("unable to fetch the supported %s languages of %q, check your API key and internet connection", languageType, cfg.Translation.SelectedService)})
+		} else if !isSupportedLanguage(supportedLangs, code) {
+			errs = append(errs, FieldError{path, fmt.Sprintf("%q is not a supported %s language of %q", code, languageType, cfg.Translation.SelectedService)})
+		}
+	}
+
+	// A blank source language is automatically detected.
+	if cfg.Translation.SourceLanguage != "" {
+		check("translation.sourceLanguage", "source", cfg.Translation.SourceLanguage)
+	}
+	check("translation.targetLanguage", "target", cfg.Translation.TargetLanguage)
+	return errs
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// appName is the name of the manga-translator directories in the standard config, cache and state locations.
+var appName = "manga-translator"
+
+// homeEnv is the environment variable which overrides the directory used for all manga-translator files.
+var homeEnv = "MTL_HOME"
+
+var (
+	home      string // Directory used for all files, overrides the standard locations if set.
+	pathsOnce sync.Once
+	configDir string
+	cacheDir  string
+	stateDir  string
+)
+
+// legacyFiles are the files which used to be kept in the "mtl" directory next to the application,
+// mapped to the directory they belong in now.
+var legacyFiles = map[string]*string{
+	"mtl-config.yml":  &configDir,
+	"mtl-cache.bin":   &cacheDir,
+	"mtl-history.bin": &stateDir,
+	"mtl-logrus.log":  &stateDir,
+}
+
+// SetHome makes all manga-translator files use the given directory instead of the standard locations.
+// This is the same as setting MTL_HOME, but takes precedence over it.
+// It must be called before any of the paths are used.
+func SetHome(dir string) {
+	home = dir
+}
+
+// Path returns the absolute path to the directory containing the config file.
+func Path() string {
+	resolvePaths()
+	return configDir
+}
+
+// CachePath returns the absolute path to the directory containing the cache.
+func CachePath() string {
+	resolvePaths()
+	return cacheDir
+}
+
+// StatePath returns the absolute path to the directory containing the logs and reading history.
+func StatePath() string {
+	resolvePaths()
+	return stateDir
+}
+
+// resolvePaths determines the config, cache and state directories the first time they are needed, creates them,
+// and moves the files of the legacy "mtl" directory into them.
+//
+// The directories are, in order of precedence:
+//   - The directory given to SetHome (--config flag), for all files.
+//   - The MTL_HOME environment variable, for all files.
+//   - The standard locations of the OS. On Linux, these are the XDG base directories
+//     ($XDG_CONFIG_HOME, $XDG_CACHE_HOME and $XDG_STATE_HOME).
+func resolvePaths() {
+	pathsOnce.Do(func() {
+		if home == "" {
+			home = os.Getenv(homeEnv)
+		}
+		if home != "" {
+			dir, err := filepath.Abs(home)
+			if err != nil {
+				log.Fatalf("error: %v", err)
+			}
+			configDir, cacheDir, stateDir = dir, dir, dir
+		} else {
+			configDir, cacheDir, stateDir = standardDirs()
+		}
+
+		for _, dir := range []string{configDir, cacheDir, stateDir} {
+			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+				log.Fatalf("error: %v", err)
+			}
+		}
+
+		if home == "" {
+			if err := migrateLegacy(); err != nil {
+				log.Warningf("Unable to move files from the legacy mtl directory: %v", err)
+			}
+		}
+	})
+}
+
+// standardDirs returns the standard config, cache and state directories for the current OS.
+func standardDirs() (configDir, cacheDir, stateDir string) {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	if runtime.GOOS == "linux" {
+		xdg := func(env, fallback string) string {
+			if dir := os.Getenv(env); filepath.IsAbs(dir) {
+				return filepath.Join(dir, appName)
+			}
+			return filepath.Join(userHome, fallback, appName)
+		}
+		return xdg("XDG_CONFIG_HOME", ".config"), xdg("XDG_CACHE_HOME", ".cache"), xdg("XDG_STATE_HOME", ".local/state")
+	}
+
+	userConfig, err := os.UserConfigDir()
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	configDir = filepath.Join(userConfig, appName)
+	return configDir, filepath.Join(userCache, appName), configDir
+}
+
+// legacyPath returns the path of the "mtl" directory next to the application, where all files used to be kept.
+func legacyPath() string {
+	applicationPath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(applicationPath, "../mtl")
+}
+
+// migrateLegacy moves the files of the legacy "mtl" directory to the standard locations,
+// unless the files already exist there. The legacy directory is removed once it is empty.
+func migrateLegacy() error {
+	legacy := legacyPath()
+	if legacy == "" {
+		return nil
+	}
+	if _, err := os.Stat(legacy); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	for name, dir := range legacyFiles {
+		src := filepath.Join(legacy, name)
+		dst := filepath.Join(*dir, name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if _, err := os.Stat(dst); err == nil {
+			log.Infof("Not moving %v, %v already exists", src, dst)
+			continue
+		}
+		log.Infof("Moving %v to %v", src, dst)
+		if err := moveFile(src, dst); err != nil {
+			return err
+		}
+	}
+
+	// Only succeeds if nothing else was left in the directory.
+	_ = os.Remove(legacy)
+	return nil
+}
+
+// moveFile moves the file at src to dst, copying it if they are on different devices.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// profileEnv is the environment variable which selects the profile, unless one is given with the --profile flag.
+var profileEnv = envPrefix + "_PROFILE"
+
+// DefaultProfile is the name used to select only the top-level fields, unless the config has a profile with that name.
+const DefaultProfile = "default"
+
+// Profile is a named set of config fields which override the top-level fields of mtl-config.yml when it is selected,
+// e.g. a different translation service and target language. Fields which are omitted keep their top-level value.
+type Profile struct {
+	CloudVision CloudVision `yaml:"cloudVision,omitempty" json:"cloudVision,omitempty"`
+	Translation Translation `yaml:"translation,omitempty" json:"translation,omitempty"`
+}
+
+// Resolve returns the config which should be used to translate: the given config file with the given profile,
+// the given series file (if not nil) and then the environment variables (see ApplyEnv) applied on top of it.
+//
+// If the profile is blank, the profile given by MTL_PROFILE, the series file, or the config file is used.
+// If none of them are set or the profile is DefaultProfile, only the top-level fields are used. The Profile field
+// of the returned config is the name of the profile which was applied, or blank if none was.
+// If no target language is set, the default of the selected service is used.
+func Resolve(file File, profile string, series *Series) (File, error) {
+	cfg := file
+	if profile == "" {
+		profile = os.Getenv(profileEnv)
+	}
+	if profile == "" && series != nil {
+		profile = series.Profile
+	}
+	if profile == "" {
+		profile = file.Profile
+	}
+
+	if profile != "" {
+		name, p, ok := file.lookupProfile(profile)
+		if ok {
+			overlay(reflect.ValueOf(&cfg.CloudVision).Elem(), reflect.ValueOf(p.CloudVision))
+			overlay(reflect.ValueOf(&cfg.Translation).Elem(), reflect.ValueOf(p.Translation))
+			profile = name
+		} else if strings.EqualFold(profile, DefaultProfile) {
+			profile = ""
+		} else {
+			return cfg, fmt.Errorf("profile %q not found, must be one of %q", profile, append(file.ProfileNames(), DefaultProfile))
+		}
+	}
+
+	if series != nil {
+		series.apply(&cfg)
+	}
+	if err := ApplyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if cfg.Translation.TargetLanguage == "" && cfg.Translation.SelectedService != "" {
+		cfg.Translation.TargetLanguage = DefaultTargetLanguage(cfg.Translation.SelectedService)
+	}
+	cfg.Profile = profile
+	return cfg, nil
+}
+
+// ProfileNames returns the sorted names of the profiles in the config.
+func (f File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Empty returns if none of the fields of the config are set.
+func (f File) Empty() bool {
+	return reflect.DeepEqual(f, File{})
+}
+
+// lookupProfile returns the profile with the given name, ignoring case since the config loader lowercases keys.
+func (f File) lookupProfile(name string) (string, Profile, bool) {
+	for key, p := range f.Profiles {
+		if strings.EqualFold(key, name) {
+			return key, p, true
+		}
+	}
+	return "", Profile{}, false
+}
+
+// overlay sets the string fields of dst to the fields of src which are not blank. Both must be the same struct type.
+func overlay(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		switch field := src.Field(i); field.Kind() {
+		case reflect.Struct:
+			overlay(dst.Field(i), field)
+		case reflect.String:
+			if field.String() != "" {
+				dst.Field(i).SetString(field.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schemaFile is the JSON schema of mtl-config.yml.
+//
+//go:embed mtl-config.schema.yml
+var schemaFile []byte
+
+// schema is the subset of JSON schema used by mtl-config.schema.yml.
+type schema struct {
+	Type                 string             `yaml:"type"`
+	Description          string             `yaml:"description"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties *additional        `yaml:"additionalProperties"`
+	Properties           map[string]*schema `yaml:"properties"`
+	Enum                 []string           `yaml:"enum"`
+}
+
+// additional is the additionalProperties of an object schema, which is either a bool which allows or forbids
+// unknown fields, or the schema of every unknown field.
+type additional struct {
+	allowed bool
+	schema  *schema
+}
+
+func (a *additional) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	return unmarshal(&a.schema)
+}
+
+// ValidateSchema checks the given mtl-config.yml contents against the config schema
+// and returns a FieldError for each field which doesn't match it.
+func ValidateSchema(data []byte) []FieldError {
+	root, err := loadSchema()
+	if err != nil {
+		// The schema is embedded, so this can only happen if it was broken during development. The config is still
+		// checked by Validate.
+		log.Errorf("Invalid config schema, skipping schema validation: %v", err)
+		return nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []FieldError{{"mtl-config.yml", fmt.Sprintf("invalid YAML, fix the syntax error: %v", err)}}
+	}
+	return root.validate("", normalize(doc))
+}
+
+// loadSchema returns the embedded config schema. Whether it matches the File struct is checked by the tests.
+func loadSchema() (*schema, error) {
+	var root schema
+	if err := yaml.Unmarshal(schemaFile, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// validate checks the given value against the schema. "path" is the path of the value in the config.
+func (s *schema) validate(path string, v interface{}) []FieldError {
+	name := path
+	if name == "" {
+		name = "mtl-config.yml"
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []FieldError{{name, fmt.Sprintf("must be an object with the fields %q", s.propertyNames())}}
+		}
+		return s.validateObject(path, obj)
+	case "integer":
+		if _, ok := v.(int); !ok {
+			return []FieldError{{name, "must be a whole number"}}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []FieldError{{name, "must be true or false"}}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return []FieldError{{name, "must be a string, quote the value or remove the field"}}
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			msg := fmt.Sprintf("%q is not allowed, must be one of %q", str, s.Enum)
+			if match := closest(s.Enum, str); match != "" {
+				msg = fmt.Sprintf("%q is not allowed, did you mean %q?", str, match)
+			}
+			return []FieldError{{name, msg}}
+		}
+	}
+	return nil
+}
+
+// validateObject checks the fields of the given object against the schema.
+func (s *schema) validateObject(path string, obj map[string]interface{}) []FieldError {
+	var errs []FieldError
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	for _, key := range s.Required {
+		if _, ok := obj[key]; !ok {
+			msg := "required field is missing"
+			if p, ok := s.Properties[key]; ok && p.Description != "" {
+				msg += ", add it: " + strings.SplitN(p.Description, "\n", 2)[0]
+			}
+			errs = append(errs, FieldError{join(key), msg})
+		}
+	}
+
+	// Sort the keys so the errors are always in the same order.
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		p, ok := s.Properties[key]
+		if !ok && s.AdditionalProperties != nil && s.AdditionalProperties.schema != nil {
+			p, ok = s.AdditionalProperties.schema, true
+		}
+		if !ok {
+			if s.AdditionalProperties != nil && !s.AdditionalProperties.allowed {
+				msg := "unknown field, remove it"
+				if match := closest(s.propertyNames(), key); match != "" {
+					msg = fmt.Sprintf("unknown field, did you mean %q?", match)
+				}
+				errs = append(errs, FieldError{join(key), msg})
+			}
+			continue
+		}
+		errs = append(errs, p.validate(join(key), obj[key])...)
+	}
+	return errs
+}
+
+// drift returns the differences between the schema and the yaml fields of the given type.
+// "path" is the path of the schema in the config.
+func (s *schema) drift(path string, t reflect.Type) []string {
+	var drift []string
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if s.Type != "string" {
+			drift = append(drift, fmt.Sprintf("%s: is a string in the struct but %q in the schema", path, s.Type))
+		}
+	case reflect.Int:
+		if s.Type != "integer" {
+			drift = append(drift, fmt.Sprintf("%s: is an integer in the struct but %q in the schema", path, s.Type))
+		}
+	case reflect.Bool:
+		if s.Type != "boolean" {
+			drift = append(drift, fmt.Sprintf("%s: is a boolean in the struct but %q in the schema", path, s.Type))
+		}
+	case reflect.Map:
+		if s.Type != "object" || s.AdditionalProperties == nil || s.AdditionalProperties.schema == nil {
+			drift = append(drift, fmt.Sprintf("%s: is a map in the struct but not in the schema", path))
+			break
+		}
+		drift = append(drift, s.AdditionalProperties.schema.drift(join("*"), t.Elem())...)
+	case reflect.Struct:
+		if s.Type != "object" {
+			drift = append(drift, fmt.Sprintf("%s: is an object in the struct but %q in the schema", path, s.Type))
+			break
+		}
+		fields := map[string]bool{}
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fields[name] = true
+			p, ok := s.Properties[name]
+			if !ok {
+				drift = append(drift, fmt.Sprintf("%s: is missing from the schema", join(name)))
+				continue
+			}
+			drift = append(drift, p.drift(join(name), t.Field(i).Type)...)
+		}
+		for _, name := range s.propertyNames() {
+			if !fields[name] {
+				drift = append(drift, fmt.Sprintf("%s: is missing from the struct", join(name)))
+			}
+		}
+	}
+	return drift
+}
+
+// propertyNames returns the sorted names of the properties of the schema.
+func (s *schema) propertyNames() []string {
+	var names []string
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalize converts the maps decoded by yaml.v2 to maps with string keys.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalize(val)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	}
+	return v
+}
+
+// contains returns if the given slice contains the given string.
+func contains(list []string, s string) bool {
+	for _, i := range list {
+		if i == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closest returns the string in the given list which only differs from the given string by case, if there is one.
+func closest(list []string, s string) string {
+	for _, i := range list {
+		if strings.EqualFold(i, s) {
+			return i
+		}
+	}
+	return ""
+}
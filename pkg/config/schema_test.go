@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSchemaMatchesFile checks that mtl-config.schema.yml is updated whenever the File struct is.
+func TestSchemaMatchesFile(t *testing.T) {
+	root, err := loadSchema()
+	if err != nil {
+		t.Fatalf("invalid config schema: %v", err)
+	}
+	if drift := root.drift("", reflect.TypeOf(File{})); len(drift) > 0 {
+		t.Errorf("config schema does not match the File struct:\n%s", strings.Join(drift, "\n"))
+	}
+}
+
+func TestDriftReportsDifferences(t *testing.T) {
+	type inner struct {
+		Name string `yaml:"name"`
+	}
+	type file struct {
+		Inner inner `yaml:"inner"`
+		Count int   `yaml:"count"`
+	}
+	s := &schema{Type: "object", Properties: map[string]*schema{
+		"inner": {Type: "object", Properties: map[string]*schema{"name": {Type: "integer"}}},
+		"extra": {Type: "string"},
+	}}
+
+	got := s.drift("", reflect.TypeOf(file{}))
+	want := []string{
+		`inner.name: is a string in the struct but "integer" in the schema`,
+		"count: is missing from the schema",
+		"extra: is missing from the struct",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got drift %q, want %q", got, want)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	base := "cloudVision:\n  credentialsPath: key.json\n"
+	tests := []struct {
+		name string
+		yml  string
+		path string // Path of the expected error, blank if the config is valid.
+	}{
+		{"valid", base + "translation:\n  selectedService: google\n", ""},
+		{"unknown service", base + "translation:\n  selectedService: bing\n", "translation.selectedService"},
+		{"wrong type", base + "translation:\n  selectedService: google\npreload:\n  workers: many\n", "preload.workers"},
+		{"invalid yaml", "translation: [\n", "mtl-config.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateSchema([]byte(tt.yml))
+			if tt.path == "" {
+				if len(errs) > 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if !hasPath(errs, tt.path) {
+				t.Fatalf("got errors %v, want one for %s", errs, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SeriesFile is the name of the per-series settings file.
+var SeriesFile = ".mtl-series.yml"
+
+// ReadingDirections are the supported reading directions of a series.
+var ReadingDirections = []string{"rtl", "ltr"}
+
+// Series is the .mtl-series.yml structure, the settings of a single series. It is kept in the directory of a chapter
+// or in its parent directory (the series directory), and its fields override the config for that chapter.
+type Series struct {
+	Profile          string            `yaml:"profile,omitempty"` // Profile used unless one is given with --profile or MTL_PROFILE.
+	ReadingDirection string            `yaml:"readingDirection,omitempty"`
+	Glossary         map[string]string `yaml:"glossary,omitempty"` // Source terms mapped to their translations.
+	CloudVision      CloudVision       `yaml:"cloudVision,omitempty"`
+	Translation      Translation       `yaml:"translation,omitempty"`
+
+	path string
+}
+
+// FindSeries returns the series file in the given chapter directory or its parent directory,
+// or nil if there is none.
+func FindSeries(dir string) (*Series, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	for _, d := range []string{dir, filepath.Dir(dir)} {
+		path := filepath.Join(d, SeriesFile)
+		data, err := ioutil.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var s Series
+		if err := yaml.UnmarshalStrict(data, &s); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if s.ReadingDirection != "" && !contains(ReadingDirections, s.ReadingDirection) {
+			return nil, fmt.Errorf("%s: readingDirection %q is not allowed, must be one of %q", path, s.ReadingDirection, ReadingDirections)
+		}
+		for term := range s.Glossary {
+			// An empty term would be inserted between every character of the text.
+			if strings.TrimSpace(term) == "" {
+				return nil, fmt.Errorf("%s: glossary has an empty term", path)
+			}
+		}
+		s.path = path
+		return &s, nil
+	}
+	return nil, nil
+}
+
+// Path returns the path of the series file.
+func (s *Series) Path() string {
+	return s.path
+}
+
+// apply sets the fields of the given config which are set in the series file.
+func (s *Series) apply(cfg *File) {
+	overlay(reflect.ValueOf(&cfg.CloudVision).Elem(), reflect.ValueOf(s.CloudVision))
+	overlay(reflect.ValueOf(&cfg.Translation).Elem(), reflect.ValueOf(s.Translation))
+	cfg.Series = s.path
+	cfg.ReadingDirection = s.ReadingDirection
+	cfg.Glossary = s.Glossary
+}
+
+// RightToLeft returns if pages are read from right to left, which is the default.
+func (f File) RightToLeft() bool {
+	return f.ReadingDirection != "ltr"
+}
+
+// GlossaryDigest returns a digest of the glossary, which changes whenever a term or its translation does. Blank if
+// there is no glossary.
+func (f File) GlossaryDigest() string {
+	if len(f.Glossary) == 0 {
+		return ""
+	}
+	terms := make([]string, 0, len(f.Glossary))
+	for term := range f.Glossary {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	h := sha256.New()
+	for _, term := range terms {
+		// Terms and translations can't contain a NUL byte, so the pairs can't be confused with each other.
+		fmt.Fprintf(h, "%s\x00%s\x00", term, f.Glossary[term])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ApplyGlossary returns the given texts with the glossary terms replaced by their translations,
+// so the translation service keeps them as they are. Longer terms are replaced first.
+func (f File) ApplyGlossary(txt []string) []string {
+	if len(f.Glossary) == 0 {
+		return txt
+	}
+	terms := make([]string, 0, len(f.Glossary))
+	for term := range f.Glossary {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	pairs := make([]string, 0, 2*len(terms))
+	for _, term := range terms {
+		pairs = append(pairs, term, f.Glossary[term])
+	}
+	r := strings.NewReplacer(pairs...)
+
+	replaced := make([]string, len(txt))
+	for i, t := range txt {
+		replaced[i] = r.Replace(t)
+	}
+	return replaced
+}
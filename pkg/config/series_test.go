@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlossaryDigest(t *testing.T) {
+	glossary := File{Glossary: map[string]string{"ルフィ": "Luffy", "ゾロ": "Zoro"}}
+	digest := glossary.GlossaryDigest()
+	if len(digest) != 16 {
+		t.Fatalf("got digest %q, want 16 hexadecimal digits", digest)
+	}
+	if got := (File{}).GlossaryDigest(); got != "" {
+		t.Errorf("got digest %q without a glossary, want none", got)
+	}
+
+	same := File{Glossary: map[string]string{"ゾロ": "Zoro", "ルフィ": "Luffy"}}
+	for i := 0; i < 10; i++ {
+		// Maps are iterated in a random order.
+		if got := same.GlossaryDigest(); got != digest {
+			t.Fatalf("got digest %q for the same glossary, want %q", got, digest)
+		}
+	}
+
+	for name, changed := range map[string]map[string]string{
+		"translation": {"ルフィ": "Luffy", "ゾロ": "Zolo"},
+		"term":        {"ルフィ": "Luffy", "ゾロ!": "Zoro"},
+		"added":       {"ルフィ": "Luffy", "ゾロ": "Zoro", "ナミ": "Nami"},
+		"moved":       {"ルフィゾロ": "Luffy", "": "Zoro"},
+	} {
+		if got := (File{Glossary: changed}).GlossaryDigest(); got == digest {
+			t.Errorf("changed %s has the same digest", name)
+		}
+	}
+}
+
+func TestFindSeries(t *testing.T) {
+	series := t.TempDir()
+	chapter := filepath.Join(series, "Chapter 1")
+	if err := os.Mkdir(chapter, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := FindSeries(chapter); s != nil || err != nil {
+		t.Fatalf("FindSeries() without a series file = %v, %v, want nothing", s, err)
+	}
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(series, SeriesFile), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("readingDirection: ltr\nglossary:\n  ルフィ: Luffy\n")
+	s, err := FindSeries(chapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Path() != filepath.Join(series, SeriesFile) || s.ReadingDirection != "ltr" || s.Glossary["ルフィ"] != "Luffy" {
+		t.Errorf("got %+v from the series directory", s)
+	}
+
+	for name, content := range map[string]string{
+		"empty term":        "glossary:\n  \"\": Luffy\n",
+		"blank term":        "glossary:\n  \" \": Luffy\n",
+		"reading direction": "readingDirection: ttb\n",
+		"unknown field":     "glosary:\n  ルフィ: Luffy\n",
+	} {
+		write(content)
+		if _, err := FindSeries(chapter); err == nil || !strings.Contains(err.Error(), SeriesFile) {
+			t.Errorf("%s: got error %v, want an error naming the series file", name, err)
+		}
+	}
+}
@@ -1,53 +1,70 @@
 package config
 
 import (
+	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"os"
-	"path/filepath"
 )
 
-// Setup loads the config at the given path into the given config object.
-func Setup(configPath string, cfg *File) {
-	log.Debugf("Config path: %v", configPath)
-	viper.AddConfigPath(configPath)
-	viper.SetConfigName("mtl-config")
-	viper.SetConfigType("yaml")
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Warning("Config file not found")
-			return
-		} else {
-			log.Fatalf("Config file was found but another error was produced: %v", err)
-		}
+// Setup loads the config at the given path into the given config object, and applies the given profile, series file
+// (may be nil) and the environment variable overrides on top of it (see Resolve). This is the config which should be
+// used to translate. An error is returned if the config file can't be read or the profile doesn't exist, and the
+// given config object is left unchanged.
+func Setup(configPath, profile string, series *Series, cfg *File) error {
+	var file File
+	if err := Read(configPath, &file); err != nil {
+		return err
 	}
-
-	err := viper.Unmarshal(cfg)
+	resolved, err := Resolve(file, profile, series)
 	if err != nil {
-		log.Fatalf("Unable to unmarshal config: %v", err)
+		return err
 	}
-	err = os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", cfg.CloudVision.CredentialsPath)
-	if err != nil {
-		log.Fatalf("Unable set GOOGLE_APPLICATION_CREDENTIALS: %v", err)
+	if err := SetCredentials(resolved); err != nil {
+		return fmt.Errorf("unable to set GOOGLE_APPLICATION_CREDENTIALS: %w", err)
 	}
+	*cfg = resolved
+	return nil
 }
 
-// Path returns the absolute path to the manga-translator files directory.
-func Path() string {
-	applicationPath, err := os.Executable()
-	if err != nil {
+// Load loads the config file at the given path into the given config object, without any overrides.
+// This is the config which should be edited and saved, so that secrets given through the environment
+// are never written to the file. Exits if the config file can't be read, see Read.
+func Load(configPath string, cfg *File) {
+	if err := Read(configPath, cfg); err != nil {
 		log.Fatal(err)
 	}
-	// Since applicationPath includes the application itself,
-	// we must add ../ so that mtl-settings will be in the same directory as the application.
-	settingsPath := filepath.Join(applicationPath, "../mtl")
-	// Only create the directory if it does not exist.
-	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
-		err = os.Mkdir(settingsPath, os.ModePerm)
-		if err != nil {
-			log.Fatalf("error: %v", err)
+}
+
+// Read is like Load, but returns an error if the config file can't be read. A missing config file is not an error,
+// the config object is left empty.
+func Read(configPath string, cfg *File) error {
+	log.Debugf("Config path: %v", configPath)
+	if err := Migrate(configPath); err != nil {
+		log.Errorf("Unable to migrate config: %v", err)
+	}
+	// A new instance for each read, since the config may be read by the UI while the file watcher reads it too.
+	v := viper.New()
+	v.AddConfigPath(configPath)
+	v.SetConfigName("mtl-config")
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			log.Warning("Config file not found")
+			return nil
 		}
+		return fmt.Errorf("unable to read the config file: %w", err)
+	}
+
+	var file File
+	if err := v.Unmarshal(&file); err != nil {
+		return fmt.Errorf("unable to unmarshal config: %w", err)
 	}
+	*cfg = file
+	return nil
+}
 
-	return settingsPath
+// SetCredentials makes the Google Cloud clients use the service account key of the given config.
+func SetCredentials(cfg File) error {
+	return os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", cfg.CloudVision.CredentialsPath)
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mtl-config-test")
+	if err != nil {
+		panic(err)
+	}
+	// The paths are resolved once, so every test uses the same config directory.
+	SetHome(dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestSaveConfig(t *testing.T) {
+	path := filepath.Join(Path(), "mtl-config.yml")
+	defer os.RemoveAll(path)
+
+	cfg := File{Translation: Translation{SelectedService: "google", TargetLanguage: "de"}}
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	var saved File
+	if err := Read(Path(), &saved); err != nil || saved.Translation.TargetLanguage != "de" || saved.Version != CurrentVersion {
+		t.Errorf("Read() = %v, config %+v, want the saved config", err, saved)
+	}
+
+	// The file can't be written, e.g. the directory is read-only or the disk is full.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfig(cfg); err == nil {
+		t.Error("SaveConfig() returned no error when the file can't be written")
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	cfg := File{Profile: "unchanged"}
+	if err := Read(dir, &cfg); err != nil || cfg.Profile != "unchanged" {
+		t.Errorf("Read() without a config file = %v, profile %q, want no error and the config unchanged", err, cfg.Profile)
+	}
+
+	path := filepath.Join(dir, "mtl-config.yml")
+	// Half-saved by an editor.
+	if err := os.WriteFile(path, []byte("translation:\n  targetLanguage: [en\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Read(dir, &cfg); err == nil || cfg.Profile != "unchanged" {
+		t.Errorf("Read() of invalid YAML = %v, profile %q, want an error and the config unchanged", err, cfg.Profile)
+	}
+
+	if err := os.WriteFile(path, []byte("translation:\n  targetLanguage: de\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Read(dir, &cfg); err != nil || cfg.Translation.TargetLanguage != "de" || cfg.Profile != "" {
+		t.Errorf("Read() = %v, config %+v, want only the fields of the file", err, cfg)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import "time"
+
+// Default timeouts of the API requests for a single page.
+var (
+	defaultDetectTimeout    = 60 * time.Second
+	defaultTranslateTimeout = 30 * time.Second
+)
+
+// Timeouts is the timeouts section of mtl-config.yml, the maximum time to wait for the API requests of a single page.
+// The values are durations such as "30s", blank values use the defaults.
+type Timeouts struct {
+	Detect    string `yaml:"detect,omitempty" json:"detect,omitempty"`
+	Translate string `yaml:"translate,omitempty" json:"translate,omitempty"`
+}
+
+// Preload is the preload section of mtl-config.yml, which controls how pages are loaded in the background.
+type Preload struct {
+	// CancelOutside cancels the requests of pages which are no longer near the current page, to save quota.
+	CancelOutside bool `yaml:"cancelOutside,omitempty" json:"cancelOutside,omitempty"`
+	// Workers is the number of pages which are loaded at the same time.
+	Workers int `yaml:"workers,omitempty" json:"workers,omitempty"`
+	// Ahead and Behind are the number of pages (or spreads) after and before the current page which are loaded.
+	// Blank values use the defaults, negative values load none.
+	Ahead  int `yaml:"ahead,omitempty" json:"ahead,omitempty"`
+	Behind int `yaml:"behind,omitempty" json:"behind,omitempty"`
+	// Chapter loads all pages of the chapter in the background, the pages nearest to the current page first.
+	Chapter bool `yaml:"chapter,omitempty" json:"chapter,omitempty"`
+}
+
+// defaultWorkers is the number of pages which are loaded at the same time, unless configured otherwise.
+var defaultWorkers = 3
+
+// WorkerCount returns the number of pages which are loaded at the same time.
+func (p Preload) WorkerCount() int {
+	if p.Workers <= 0 {
+		return defaultWorkers
+	}
+	return p.Workers
+}
+
+// Default number of pages (or spreads) which are loaded after and before the current page.
+var (
+	defaultAhead  = 2
+	defaultBehind = 1
+)
+
+// AheadCount returns the number of pages (or spreads) after the current page which are loaded.
+func (p Preload) AheadCount() int {
+	return pageCount(p.Ahead, defaultAhead)
+}
+
+// BehindCount returns the number of pages (or spreads) before the current page which are loaded.
+func (p Preload) BehindCount() int {
+	return pageCount(p.Behind, defaultBehind)
+}
+
+// pageCount returns the given number of pages, the given default if it is blank, or 0 if it is negative.
+func pageCount(n, def int) int {
+	switch {
+	case n == 0:
+		return def
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// DetectTimeout returns the maximum time to wait for the text of a page to be detected. Tall pages which are split
+// into tiles wait this long for each tile.
+func (t Timeouts) DetectTimeout() time.Duration {
+	return parseTimeout(t.Detect, defaultDetectTimeout)
+}
+
+// TranslateTimeout returns the maximum time to wait for the text of a page to be translated.
+func (t Timeouts) TranslateTimeout() time.Duration {
+	return parseTimeout(t.Translate, defaultTranslateTimeout)
+}
+
+// parseTimeout returns the given duration, or the given default if it is blank or invalid.
+func parseTimeout(s string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
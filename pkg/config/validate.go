@@ -0,0 +1,176 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FieldError is a problem with a single field of the config.
+type FieldError struct {
+	Path    string // Path of the field in mtl-config.yml, e.g. "translation.deepL.apiKey".
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Services are the supported translation services.
+var Services = []string{"google", "deepL"}
+
+// languageCode matches ISO-639-1 codes with an optional region or script, e.g. "ja", "EN-US" or "zh-Hant".
+var languageCode = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,4})?$`)
+
+// serviceAccountKey is the part of a service account key file needed to authenticate.
+type serviceAccountKey struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// Check validates the config file against the config schema, and the given config (loaded from that file
+// and the environment) with Validate. Returns a FieldError for each problem found.
+func Check(cfg File) []FieldError {
+	data, err := ioutil.ReadFile(filepath.Join(Path(), "mtl-config.yml"))
+	if errors.Is(err, os.ErrNotExist) {
+		// The whole config may be given through environment variables.
+		return Validate(cfg)
+	} else if err != nil {
+		return []FieldError{{"mtl-config.yml", fmt.Sprintf("unable to read: %v", err)}}
+	}
+
+	errs := ValidateSchema(data)
+	// Skip the problems which were already reported by the schema.
+	for _, err := range Validate(cfg) {
+		if !hasPath(errs, err.Path) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Validate checks the given config for missing or invalid fields and returns a FieldError for each of them.
+func Validate(cfg File) []FieldError {
+	var errs []FieldError
+
+	if err := checkCredentials(cfg.CloudVision.CredentialsPath); err != "" {
+		errs = append(errs, FieldError{"cloudVision.credentialsPath", err})
+	}
+
+	switch service := cfg.Translation.SelectedService; {
+	case service == "google":
+	case service == "deepL":
+		if cfg.Translation.DeepL.APIKey == "" {
+			errs = append(errs, FieldError{"translation.deepL.apiKey", `required when the selected service is "deepL", copy it from your DeepL account`})
+		}
+	case service == "":
+		errs = append(errs, FieldError{"translation.selectedService", fmt.Sprintf("required, must be one of %q", Services)})
+	default:
+		msg := fmt.Sprintf("%q is not a supported service, must be one of %q", service, Services)
+		if match := closest(Services, service); match != "" {
+			msg = fmt.Sprintf("%q is not a supported service, did you mean %q?", service, match)
+		}
+		errs = append(errs, FieldError{"translation.selectedService", msg})
+	}
+
+	if err := checkLanguage(cfg.Translation.SourceLanguage); err != "" {
+		errs = append(errs, FieldError{"translation.sourceLanguage", err})
+	}
+	if err := checkLanguage(cfg.Translation.TargetLanguage); err != "" {
+		errs = append(errs, FieldError{"translation.targetLanguage", err})
+	}
+
+	if err := checkTimeout(cfg.Timeouts.Detect); err != "" {
+		errs = append(errs, FieldError{"timeouts.detect", err})
+	}
+	if err := checkTimeout(cfg.Timeouts.Translate); err != "" {
+		errs = append(errs, FieldError{"timeouts.translate", err})
+	}
+	if _, err := ParseSize(cfg.Cache.MaxSize); err != nil {
+		errs = append(errs, FieldError{"cache.maxSize", fmt.Sprintf(`%q is not a valid size, use a number with a unit such as "500MB" or "2GB"`, cfg.Cache.MaxSize)})
+	}
+	if cfg.Cache.Similarity < 0 || cfg.Cache.Similarity > 64 {
+		errs = append(errs, FieldError{"cache.similarity", "must be between 0 and 64"})
+	}
+	if cfg.Cache.Server != "" {
+		if u, err := url.Parse(cfg.Cache.Server); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, FieldError{"cache.server", fmt.Sprintf(`%q is not a valid URL, use e.g. "http://cache.example.com:8080"`, cfg.Cache.Server)})
+		}
+		if cfg.Cache.Token == "" {
+			errs = append(errs, FieldError{"cache.token", "required with cache.server, set it to the token of the cache server"})
+		}
+	}
+
+	return errs
+}
+
+// checkCredentials returns a message explaining the problem with the service account key at the given path,
+// or an empty string if there is none.
+func checkCredentials(path string) string {
+	if path == "" {
+		return "required, set it to the path of your Vision API service account key"
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("file %q not found, check the path of your service account key", path)
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return fmt.Sprintf("file %q is not valid JSON, download the service account key again", path)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return fmt.Sprintf("file %q is not a service account key, create one for the Vision API", path)
+	}
+	return ""
+}
+
+// checkLanguage returns a message explaining why the given language code is not plausible,
+// or an empty string if there is none. Only the format is checked, the supported languages
+// of the selected service are checked by ValidateLanguages.
+func checkLanguage(code string) string {
+	if code == "" {
+		return ""
+	}
+	if !languageCode.MatchString(code) {
+		return fmt.Sprintf(`%q is not a language code, use an ISO-639-1 code such as "ja" or "en"`, code)
+	}
+	return ""
+}
+
+// checkTimeout returns a message explaining why the given timeout is invalid, or an empty string if it is valid.
+func checkTimeout(timeout string) string {
+	if timeout == "" {
+		return ""
+	}
+	if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+		return fmt.Sprintf(`%q is not a valid duration, use a positive number with a unit such as "30s" or "2m"`, timeout)
+	}
+	return ""
+}
+
+// hasPath returns if the given list of errors contains an error for the given path.
+func hasPath(errs []FieldError, path string) bool {
+	for _, err := range errs {
+		if err.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatErrors returns the given errors as a multi-line message, one error per line.
+func FormatErrors(errs []FieldError) string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "• " + err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// watchDelay is how long to wait for more changes before reporting a change to the config file,
+// since editors often write a file in several steps.
+var watchDelay = 250 * time.Millisecond
+
+var watchOnce sync.Once
+
+// Watch calls onChange whenever the config file in the config directory is created, changed or removed.
+// The directory is watched instead of the file, so a config file created after startup is picked up, as well as
+// editors which replace the file instead of writing to it. It can only be called once.
+func Watch(onChange func()) error {
+	err := errors.New("already watching the config file")
+	watchOnce.Do(func() {
+		err = watch(Path(), "mtl-config.yml", onChange)
+	})
+	return err
+}
+
+// watch calls onChange when the file with the given name in the given directory changes, once no more changes were
+// made for watchDelay.
+func watch(dir, name string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	log.Infof("Watching config file: %v", filepath.Join(dir, name))
+
+	go func() {
+		var (
+			mu    sync.Mutex
+			timer *time.Timer
+		)
+		for {
+			select {
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(e.Name) != name || e.Op == fsnotify.Chmod {
+					continue
+				}
+				log.Debugf("Config file changed: %v", e)
+				mu.Lock()
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDelay, onChange)
+				mu.Unlock()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errorf("Unable to watch the config file: %v", err)
+			}
+		}
+	}()
+	return nil
+}
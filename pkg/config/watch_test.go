@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchNewFile(t *testing.T) {
+	dir := t.TempDir()
+	changed := make(chan struct{}, 10)
+	if err := watch(dir, "mtl-config.yml", func() { changed <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other files in the directory are ignored.
+	if err := os.WriteFile(filepath.Join(dir, "mtl-cache.bin"), []byte("cache"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+		t.Fatal("a change to another file was reported")
+	case <-time.After(2 * watchDelay):
+	}
+
+	// The config file didn't exist when the watch started, e.g. it is created from the settings panel.
+	if err := os.WriteFile(filepath.Join(dir, "mtl-config.yml"), []byte("version: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the new config file was not reported")
+	}
+}
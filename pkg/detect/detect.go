@@ -6,11 +6,13 @@ import (
 	"context"
 	"errors"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
 	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
 	"image"
 	"image/color"
 	_ "image/jpeg"
 	"image/png"
+	"os"
 	"strings"
 )
 
@@ -30,22 +32,53 @@ type TextBlock struct {
 	Color      color.NRGBA
 }
 
-var errInvalidVisionPath = errors.New(`path given for Vision API service account key is invalid. Please run the "manga-translator-setup" application to fix it`)
+// BorderColor returns the border color of the text block with the given index, cycling through the list of border colors.
+func BorderColor(i int) color.NRGBA {
+	return borderColors[i%len(borderColors)]
+}
+
+// Contains returns if the original or translated text of the block contains the given text, ignoring case.
+func (b TextBlock) Contains(query string) bool {
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(b.Text), query) || strings.Contains(strings.ToLower(b.Translated), query)
+}
 
-// GetAnnotation gets text (TextAnnotation) from the Vision API for an image at the given file path.
-func GetAnnotation(img *image.RGBA) (*pb.TextAnnotation, error) {
-	ctx := context.Background()
+// defaultLanguageHints are the languages the Vision API expects when no source language is configured.
+var defaultLanguageHints = []string{"ja"}
 
-	client, err := vision.NewImageAnnotatorClient(ctx)
+// languageHints returns the language hints for the Vision API for the given source language of the translation.
+func languageHints(sourceLanguage string) []string {
+	if sourceLanguage == "" {
+		return defaultLanguageHints
+	}
+	// DeepL language codes are upper case.
+	return []string{strings.ToLower(sourceLanguage)}
+}
+
+var errInvalidVisionPath = errors.New(`path given for Vision API service account key is invalid. Please run the "manga-translator-setup" application to fix it`)
+
+// NewClient creates a Vision API client which authenticates with the service account key at the given path.
+// The client should be reused for all requests, and closed when it is no longer needed.
+func NewClient(ctx context.Context, credentialsPath string) (*vision.ImageAnnotatorClient, error) {
+	// Check the key first, the errors of the client can only be told apart by their message.
+	if info, err := os.Stat(credentialsPath); err != nil || info.IsDir() {
+		log.Errorf("Vision API service account key %q: %v", credentialsPath, err)
+		return nil, errInvalidVisionPath
+	}
+	client, err := vision.NewImageAnnotatorClient(ctx, option.WithCredentialsFile(credentialsPath))
 	if err != nil {
 		log.Errorf("NewImageAnnotatorClient: %v", err)
-		if strings.HasPrefix(err.Error(),
-			"google: error getting credentials using GOOGLE_APPLICATION_CREDENTIALS environment variable") {
+		if strings.HasPrefix(err.Error(), "google: error getting credentials") {
 			return nil, errInvalidVisionPath
 		}
 		return nil, err
 	}
+	return client, nil
+}
 
+// GetAnnotation gets text (TextAnnotation) from the Vision API for the given image, which has text in the given source
+// language (or Japanese if it is blank).
+func GetAnnotation(ctx context.Context, client *vision.ImageAnnotatorClient, img *image.RGBA, sourceLanguage string) (*pb.TextAnnotation, error) {
 	reader := ReaderFromImage(img)
 	visionImg, err := vision.NewImageFromReader(reader)
 	if err != nil {
@@ -53,7 +86,7 @@ func GetAnnotation(img *image.RGBA) (*pb.TextAnnotation, error) {
 		return nil, err
 	}
 
-	annotation, err := client.DetectDocumentText(ctx, visionImg, &pb.ImageContext{LanguageHints: []string{"ja"}})
+	annotation, err := client.DetectDocumentText(ctx, visionImg, &pb.ImageContext{LanguageHints: languageHints(sourceLanguage)})
 	if err != nil {
 		log.Errorf("DetectDocumentText: %v", err)
 		return nil, err
@@ -61,7 +94,7 @@ func GetAnnotation(img *image.RGBA) (*pb.TextAnnotation, error) {
 
 	if annotation == nil {
 		log.Info("No text found")
-		return nil, errors.New("no text found")
+		return nil, errNoText
 	} else {
 		log.WithField("text", annotation.Text).Info("Detected Text")
 		return annotation, nil
@@ -89,7 +122,7 @@ func OrganizeAnnotation(annotation *pb.TextAnnotation) []TextBlock {
 			blockList = append(blockList, TextBlock{
 				Text:     b,
 				Vertices: block.BoundingBox.Vertices,
-				Color:    borderColors[i%len(borderColors)], // Cycle through the list of border colors.
+				Color:    BorderColor(i),
 			})
 		}
 	}
@@ -0,0 +1,34 @@
+package detect
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewClientInvalidPath(t *testing.T) {
+	dir := t.TempDir()
+	for name, path := range map[string]string{
+		"missing":   filepath.Join(dir, "missing.json"),
+		"directory": dir,
+		"blank":     "",
+	} {
+		if _, err := NewClient(context.Background(), path); !errors.Is(err, errInvalidVisionPath) {
+			t.Errorf("%s key: got error %v, want errInvalidVisionPath", name, err)
+		}
+	}
+}
+
+func TestLanguageHints(t *testing.T) {
+	for source, want := range map[string][]string{
+		"":   {"ja"},
+		"JA": {"ja"},
+		"ko": {"ko"},
+	} {
+		if got := languageHints(source); !reflect.DeepEqual(got, want) {
+			t.Errorf("languageHints(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
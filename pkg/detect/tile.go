@@ -0,0 +1,185 @@
+package detect
+
+import (
+	vision "cloud.google.com/go/vision/apiv1"
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"image"
+	"image/draw"
+	"time"
+)
+
+// Very tall images (e.g. webtoon strips) are downscaled by the Vision API until their text is illegible,
+// so images taller than maxTileAspect times their width are cut into overlapping tiles which are detected one at a
+// time. Pages with a normal aspect ratio are detected in one request, however large they are.
+var (
+	maxTileHeight         = 2400 // hard-coded, only limits the size of the tiles.
+	maxTileAspect float32 = 2.0  // hard-coded
+	tileOverlap           = 0.15 // Fraction of the tile height shared with the next tile.
+)
+
+// duplicateThreshold is the fraction of the smaller block's area which must be covered by another block
+// for the two blocks to be considered the same block detected in two overlapping tiles.
+var duplicateThreshold float32 = 0.5 // hard-coded
+
+var errNoText = errors.New("no text found")
+
+// tileHeight returns the height of the tiles used to detect text in an image with the given dimensions.
+func tileHeight(width int) int {
+	h := int(float32(width) * maxTileAspect)
+	if h > maxTileHeight || h <= 0 {
+		h = maxTileHeight
+	}
+	return h
+}
+
+// NeedsTiling returns if an image with the given dimensions is too tall for its width to be detected in a single
+// request.
+func NeedsTiling(width, height int) bool {
+	return float32(height) > float32(width)*maxTileAspect
+}
+
+// GetTextBlocks detects the text in the given source language (see GetAnnotation) in the given image and returns it as
+// a slice of TextBlocks. Each request to the Vision API is cancelled after the given timeout.
+// Images which are too tall are split into overlapping tiles, and the blocks of each tile are
+// mapped back to the coordinates of the full image.
+func GetTextBlocks(ctx context.Context, client *vision.ImageAnnotatorClient, img *image.RGBA, sourceLanguage string, timeout time.Duration) ([]TextBlock, error) {
+	annotate := func(img *image.RGBA) (*pb.TextAnnotation, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return GetAnnotation(ctx, client, img, sourceLanguage)
+	}
+
+	b := img.Bounds()
+	if !NeedsTiling(b.Dx(), b.Dy()) {
+		annotation, err := annotate(img)
+		if err != nil {
+			return nil, err
+		}
+		return OrganizeAnnotation(annotation), nil
+	}
+
+	tiles := tileRects(b)
+	log.Infof("Image is too tall, detecting text in %d tiles", len(tiles))
+
+	var blockList []TextBlock
+	for i, r := range tiles {
+		log.Debugf("Detecting text in tile %d: %v", i, r)
+		tile := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+		draw.Draw(tile, tile.Bounds(), img, r.Min, draw.Src)
+
+		annotation, err := annotate(tile)
+		if errors.Is(err, errNoText) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, block := range OrganizeAnnotation(annotation) {
+			block.Vertices = offsetVertices(block.Vertices, r.Min)
+			blockList = append(blockList, block)
+		}
+	}
+
+	blockList = removeDuplicates(blockList)
+	if len(blockList) == 0 {
+		return nil, errNoText
+	}
+
+	// Blocks were colored per tile, recolor them so neighbouring blocks can be told apart.
+	for i := range blockList {
+		blockList[i].Color = BorderColor(i)
+	}
+	return blockList, nil
+}
+
+// tileRects splits the given bounds into overlapping tiles, from top to bottom.
+func tileRects(b image.Rectangle) []image.Rectangle {
+	h := tileHeight(b.Dx())
+	step := h - int(float64(h)*tileOverlap)
+
+	var tiles []image.Rectangle
+	for y := b.Min.Y; ; y += step {
+		r := image.Rect(b.Min.X, y, b.Max.X, y+h).Intersect(b)
+		tiles = append(tiles, r)
+		if r.Max.Y >= b.Max.Y {
+			break
+		}
+	}
+	return tiles
+}
+
+// offsetVertices returns a copy of the given vertices moved by the given offset.
+func offsetVertices(vertices []*pb.Vertex, offset image.Point) []*pb.Vertex {
+	moved := make([]*pb.Vertex, len(vertices))
+	for i, v := range vertices {
+		moved[i] = &pb.Vertex{
+			X: v.X + int32(offset.X),
+			Y: v.Y + int32(offset.Y),
+		}
+	}
+	return moved
+}
+
+// blockRect returns the axis-aligned bounding rectangle of the given block.
+func blockRect(block TextBlock) image.Rectangle {
+	var r image.Rectangle
+	for i, v := range block.Vertices {
+		p := image.Pt(int(v.X), int(v.Y))
+		if i == 0 {
+			r = image.Rectangle{Min: p, Max: p}
+			continue
+		}
+		r = r.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+	return r
+}
+
+// area returns the area of the given rectangle.
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// removeDuplicates removes blocks which were detected twice in the overlap zone of two tiles.
+// The larger of the two blocks is kept, since the smaller one was most likely cut off by the edge of its tile.
+func removeDuplicates(blocks []TextBlock) []TextBlock {
+	removed := make([]bool, len(blocks))
+	for i := range blocks {
+		if removed[i] {
+			continue
+		}
+		ri := blockRect(blocks[i])
+		for j := i + 1; j < len(blocks); j++ {
+			if removed[j] {
+				continue
+			}
+			rj := blockRect(blocks[j])
+			overlap := area(ri.Intersect(rj))
+			smaller := area(ri)
+			if area(rj) < smaller {
+				smaller = area(rj)
+			}
+			if smaller == 0 || float32(overlap)/float32(smaller) < duplicateThreshold {
+				continue
+			}
+
+			if area(rj) > area(ri) {
+				log.Debugf("Removing duplicate block: %q", blocks[i].Text)
+				removed[i] = true
+				break
+			}
+			log.Debugf("Removing duplicate block: %q", blocks[j].Text)
+			removed[j] = true
+		}
+	}
+
+	var unique []TextBlock
+	for i, block := range blocks {
+		if !removed[i] {
+			unique = append(unique, block)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,194 @@
+package detect
+
+import (
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestTileRects(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds image.Rectangle
+		want   []image.Rectangle
+	}{
+		{
+			// Tiles are 2000 high (twice the width), and start 1700 apart (15% overlap).
+			name:   "shorter than one tile",
+			bounds: image.Rect(0, 0, 1000, 1500),
+			want:   []image.Rectangle{image.Rect(0, 0, 1000, 1500)},
+		},
+		{
+			name:   "exactly one tile",
+			bounds: image.Rect(0, 0, 1000, 2000),
+			want:   []image.Rectangle{image.Rect(0, 0, 1000, 2000)},
+		},
+		{
+			name:   "exact multiple",
+			bounds: image.Rect(0, 0, 1000, 3700),
+			want: []image.Rectangle{
+				image.Rect(0, 0, 1000, 2000),
+				image.Rect(0, 1700, 1000, 3700),
+			},
+		},
+		{
+			name:   "last partial tile",
+			bounds: image.Rect(0, 0, 1000, 4000),
+			want: []image.Rectangle{
+				image.Rect(0, 0, 1000, 2000),
+				image.Rect(0, 1700, 1000, 3700),
+				image.Rect(0, 3400, 1000, 4000),
+			},
+		},
+		{
+			// Tiles of wide images are capped at 2400, starting 2040 apart.
+			name:   "height cap",
+			bounds: image.Rect(0, 0, 1500, 5000),
+			want: []image.Rectangle{
+				image.Rect(0, 0, 1500, 2400),
+				image.Rect(0, 2040, 1500, 4440),
+				image.Rect(0, 4080, 1500, 5000),
+			},
+		},
+		{
+			name:   "offset bounds",
+			bounds: image.Rect(10, 100, 1010, 2600),
+			want: []image.Rectangle{
+				image.Rect(10, 100, 1010, 2100),
+				image.Rect(10, 1800, 1010, 2600),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tileRects(tt.bounds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got tiles %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTileHeight(t *testing.T) {
+	for _, tt := range []struct{ width, want int }{
+		{500, 1000},
+		{1200, 2400},
+		{2000, 2400},
+		{0, 2400},
+	} {
+		if got := tileHeight(tt.width); got != tt.want {
+			t.Errorf("tileHeight(%d) = %d, want %d", tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestNeedsTiling(t *testing.T) {
+	for _, tt := range []struct {
+		width, height int
+		want          bool
+	}{
+		{800, 1200, false},
+		// High resolution page, taller than a tile but not too tall for its width.
+		{1800, 2700, false},
+		{3000, 6000, false},
+		{1000, 2001, true},
+		// Webtoon strip.
+		{800, 12000, true},
+	} {
+		if got := NeedsTiling(tt.width, tt.height); got != tt.want {
+			t.Errorf("NeedsTiling(%d, %d) = %v, want %v", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+// rectBlock returns a block with the given text whose vertices are the corners of the given rectangle.
+func rectBlock(text string, x0, y0, x1, y1 int32) TextBlock {
+	return TextBlock{Text: text, Vertices: []*pb.Vertex{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}}}
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []TextBlock
+		want   []string // Text of the remaining blocks.
+	}{
+		{
+			name: "same block in both tiles",
+			blocks: []TextBlock{
+				rectBlock("a", 100, 1750, 300, 1900),
+				rectBlock("a", 100, 1750, 300, 1900),
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "block cut off by the bottom of the first tile",
+			blocks: []TextBlock{
+				rectBlock("cut", 100, 1900, 300, 1999),
+				rectBlock("whole", 100, 1900, 300, 2100),
+			},
+			want: []string{"whole"},
+		},
+		{
+			name: "block cut off by the top of the second tile",
+			blocks: []TextBlock{
+				rectBlock("whole", 100, 1650, 300, 1800),
+				rectBlock("cut", 100, 1700, 300, 1800),
+			},
+			want: []string{"whole"},
+		},
+		{
+			name: "neighbouring blocks overlapping less than half",
+			blocks: []TextBlock{
+				rectBlock("a", 0, 1700, 100, 1800),
+				rectBlock("b", 60, 1700, 160, 1800),
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "separate blocks",
+			blocks: []TextBlock{
+				rectBlock("a", 0, 0, 100, 100),
+				rectBlock("b", 0, 1800, 100, 1900),
+				rectBlock("c", 200, 1800, 300, 1900),
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "three detections of one block",
+			blocks: []TextBlock{
+				rectBlock("top", 100, 1900, 300, 1999),
+				rectBlock("whole", 100, 1900, 300, 2100),
+				rectBlock("bottom", 100, 2000, 300, 2100),
+			},
+			want: []string{"whole"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, b := range removeDuplicates(tt.blocks) {
+				got = append(got, b.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got blocks %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveDuplicatesThreshold(t *testing.T) {
+	// The vertices are inclusive, so the smaller block is 100x100 pixels and the overlap is 50 pixels wide.
+	overlapping := []TextBlock{
+		rectBlock("a", 0, 0, 99, 99),
+		rectBlock("b", 50, 0, 199, 99),
+	}
+	if got := removeDuplicates(overlapping); len(got) != 1 || got[0].Text != "b" {
+		t.Errorf("blocks overlapping exactly half were not merged: %v", got)
+	}
+
+	overlapping[1] = rectBlock("b", 51, 0, 199, 99)
+	if got := removeDuplicates(overlapping); len(got) != 2 {
+		t.Errorf("blocks overlapping less than half were merged: %v", got)
+	}
+}
@@ -0,0 +1,191 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is the reading position of a chapter (a directory or set of images).
+type Entry struct {
+	Key      string   // Hash of the image set.
+	Title    string   // Name shown in the history list.
+	Paths    []string // Paths or URLs used to re-open the chapter. Empty for clipboard images.
+	Hashes   []string // Hashes of the images, in page order.
+	URL      bool     // Paths are URLs.
+	Pages    int      // Number of pages in the chapter.
+	Page     int      // Last viewed page.
+	Ratio    float32  // Window split ratio.
+	LastRead time.Time
+}
+
+var maxEntries = 20 // hard-coded
+
+var mu sync.Mutex
+
+// historyPath returns the path of the history file.
+func historyPath() string {
+	return filepath.Join(config.StatePath(), "mtl-history.bin")
+}
+
+// read reads the history from the history file, most recently read first.
+func read() []Entry {
+	var entries []Entry
+
+	historyFile, err := os.Open(historyPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return entries
+	} else if err != nil {
+		log.Errorf("Unable to open history: %v", err)
+		return entries
+	}
+	defer historyFile.Close()
+
+	dec := gob.NewDecoder(historyFile)
+	if err := dec.Decode(&entries); err != nil {
+		log.Errorf("Unable to read history: %v", err)
+	}
+	return entries
+}
+
+// write replaces the history file with the given entries.
+func write(entries []Entry) {
+	historyFile, err := os.OpenFile(historyPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Errorf("Unable to open history: %v", err)
+		return
+	}
+	defer historyFile.Close()
+
+	enc := gob.NewEncoder(historyFile)
+	if err := enc.Encode(entries); err != nil {
+		log.Errorf("History write failed: %v", err)
+	}
+}
+
+// Key returns the history key for a chapter with the given image hashes, in page order.
+// A different selection of images from the same directory is another chapter with its own reading position.
+func Key(hashes []string) string {
+	h := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+	return "set:" + hex.EncodeToString(h[:])
+}
+
+// Title returns the name shown in the history list for a chapter opened from the given paths.
+func Title(paths []string) string {
+	switch {
+	case len(paths) == 0:
+		return "Clipboard image"
+	case len(paths) == 1:
+		return filepath.Base(paths[0])
+	}
+	dir := filepath.Base(filepath.Dir(paths[0]))
+	return fmt.Sprintf("%s (%d images)", dir, len(paths))
+}
+
+// absPaths returns the given paths as absolute paths, URLs are kept as they are.
+func absPaths(imgPath []string, url bool) []string {
+	var paths []string
+	for _, p := range imgPath {
+		if !url {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// Lookup returns the history entry for the images opened from the given paths,
+// so the last reading position can be restored. A new entry is returned if the images were never opened before.
+func Lookup(imgPath, hashes []string, url, clip bool) Entry {
+	var paths []string
+	if !clip {
+		paths = absPaths(imgPath, url)
+	}
+
+	key := Key(hashes)
+	entry, ok := Get(key)
+	if !ok {
+		entry = Entry{Key: key}
+	}
+	entry.Title = Title(paths)
+	entry.Paths = paths
+	entry.Hashes = hashes
+	entry.URL = url
+	return entry
+}
+
+// Append adds the images opened from the given paths to the end of the chapter, which becomes a new image set
+// with its own key. The reading position is kept. A chapter which mixes clipboard images, local files or URLs
+// can't be re-opened, so it has no paths.
+func (e *Entry) Append(imgPath, hashes []string, url bool) {
+	if len(e.Paths) > 0 && e.URL == url {
+		e.Paths = append(e.Paths, absPaths(imgPath, url)...)
+	} else {
+		e.Paths = nil
+	}
+	e.Hashes = append(e.Hashes, hashes...)
+	e.Key = Key(e.Hashes)
+	e.Title = Title(e.Paths)
+}
+
+// Get returns the history entry with the given key, if it exists.
+func Get(key string) (Entry, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, e := range read() {
+		if e.Key == key {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Save adds the given entry to the top of the history, replacing any entry with the same key.
+func Save(entry Entry) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	log.WithFields(log.Fields{
+		"key":   entry.Key,
+		"page":  entry.Page,
+		"ratio": entry.Ratio,
+	}).Debug("Saving reading position")
+
+	entry.LastRead = time.Now()
+	entries := []Entry{entry}
+	for _, e := range read() {
+		if e.Key != entry.Key {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) > maxEntries {
+		entries = entries[:maxEntries]
+	}
+	write(entries)
+}
+
+// Recent returns the recently read chapters which can be re-opened, most recent first.
+func Recent() []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var entries []Entry
+	for _, e := range read() {
+		if len(e.Paths) > 0 {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
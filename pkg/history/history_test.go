@@ -0,0 +1,86 @@
+package history
+
+import (
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mtl-history-test")
+	if err != nil {
+		panic(err)
+	}
+	// The paths are resolved once, so every test uses the same history file.
+	config.SetHome(dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestKey(t *testing.T) {
+	key := Key([]string{"a", "b", "c"})
+	for _, tt := range []struct {
+		name   string
+		hashes []string
+	}{
+		{"subset", []string{"a", "b"}},
+		{"other order", []string{"b", "a", "c"}},
+		{"no images", nil},
+	} {
+		if Key(tt.hashes) == key {
+			t.Errorf("%s has the same key as the chapter", tt.name)
+		}
+	}
+	if Key([]string{"a", "b", "c"}) != key {
+		t.Error("got another key for the same images")
+	}
+}
+
+func TestLookupSubset(t *testing.T) {
+	if err := os.Remove(historyPath()); err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "01.png"), filepath.Join(dir, "02.png"), filepath.Join(dir, "03.png")}
+
+	chapter := Lookup(paths, []string{"a", "b", "c"}, false, false)
+	chapter.Pages, chapter.Page = 3, 2
+	Save(chapter)
+
+	if got := Lookup(paths, []string{"a", "b", "c"}, false, false); got.Page != 2 {
+		t.Errorf("got page %d for the same images, want the saved page 2", got.Page)
+	}
+	// Another selection from the same directory must not restore a page it may not have.
+	if got := Lookup(paths[:1], []string{"a"}, false, false); got.Page != 0 || got.Pages != 0 {
+		t.Errorf("got page %d of %d for a single image of the directory, want a new entry", got.Page, got.Pages)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	dir := t.TempDir()
+	chapter := Lookup([]string{filepath.Join(dir, "01.png")}, []string{"a"}, false, false)
+	chapter.Page = 1
+	chapter.Append([]string{filepath.Join(dir, "02.png"), filepath.Join(dir, "03.png")}, []string{"b", "c"}, false)
+
+	if chapter.Key != Key([]string{"a", "b", "c"}) {
+		t.Errorf("got key %q, want the key of the whole image set", chapter.Key)
+	}
+	if len(chapter.Paths) != 3 || chapter.Paths[2] != filepath.Join(dir, "03.png") || chapter.Page != 1 {
+		t.Errorf("got paths %v at page %d, want the appended paths at the same page", chapter.Paths, chapter.Page)
+	}
+	if want := Title(chapter.Paths); chapter.Title != want {
+		t.Errorf("got title %q, want %q", chapter.Title, want)
+	}
+
+	for name, c := range map[string]Entry{
+		"clipboard": Lookup(nil, []string{"a"}, false, true),
+		"URL":       Lookup([]string{"https://example.com/01.png"}, []string{"a"}, true, false),
+	} {
+		c.Append([]string{filepath.Join(dir, "02.png")}, []string{"b"}, false)
+		if len(c.Paths) != 0 || c.Key != Key([]string{"a", "b"}) {
+			t.Errorf("%s chapter with local files: got paths %v and key %q, want no paths", name, c.Paths, c.Key)
+		}
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
 	log "github.com/sirupsen/logrus"
 	"golang.design/x/clipboard"
 	drawX "golang.org/x/image/draw"
-	"hash"
+	_ "golang.org/x/image/webp"
 	"image"
 	"image/draw"
 	_ "image/jpeg"
@@ -25,6 +28,7 @@ type Dimensions struct {
 type TranslatorImage struct {
 	Image      *image.RGBA
 	Hash       string
+	PHash      uint64 // Perceptual hash of the image, see PerceptualHash.
 	Dimensions Dimensions
 	size       int
 }
@@ -33,95 +37,74 @@ type TranslatorImage struct {
 // "url" parameter specifies if the given file string is a URL.
 // "clip" parameter specifies if the image should be taken from the clipboard (overrides "url" parameter)
 func Open(file string, url, clip bool) TranslatorImage {
-	var img image.Image
-	var h hash.Hash
-	var size int
+	img, err := Load(file, url, clip)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return img
+}
 
-	if clip {
-		var err error
+// Load is the same as Open, but returns an error instead of exiting if the image can't be opened.
+func Load(file string, url, clip bool) (TranslatorImage, error) {
+	var data []byte
 
+	if clip {
 		// Init returns an error if the package is not ready for use.
-		err = clipboard.Init()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		imgByte := clipboard.Read(clipboard.FmtImage)
-		if imgByte == nil {
-			log.Fatal("Image not found in clipboard")
-		}
-
-		// Need TeeReader to read io.Reader twice without re-opening file
-		// https://stackoverflow.com/questions/39791021/how-to-read-multiple-times-from-same-io-reader
-		var buf bytes.Buffer
-		tee := io.TeeReader(bytes.NewReader(imgByte), &buf)
-
-		img, _, err = image.Decode(tee)
-		size = buf.Len()
+		err := clipboard.Init()
 		if err != nil {
-			log.Fatalf("Image decode error: %v", err)
+			return TranslatorImage{}, err
 		}
 
-		h = sha256.New()
-		if _, err := io.Copy(h, &buf); err != nil {
-			log.Fatalf("Hash error: %v", err)
+		data = clipboard.Read(clipboard.FmtImage)
+		if data == nil {
+			return TranslatorImage{}, errors.New("image not found in clipboard")
 		}
 	} else if url {
 		resp, err := http.Get(file)
 		if err != nil {
-			log.Fatal(err)
+			return TranslatorImage{}, err
 		}
 		defer resp.Body.Close()
-
-		var buf bytes.Buffer
-		tee := io.TeeReader(resp.Body, &buf)
-
-		img, _, err = image.Decode(tee)
-		size = buf.Len()
-		if err != nil {
-			log.Fatalf("Image decode error: %v", err)
+		if resp.StatusCode != http.StatusOK {
+			return TranslatorImage{}, fmt.Errorf("download failed: %v", resp.Status)
 		}
 
-		h = sha256.New()
-		if _, err := io.Copy(h, &buf); err != nil {
-			log.Fatalf("Hash error: %v", err)
-		}
-	} else {
-		f, err := os.Open(filepath.ToSlash(file))
+		data, err = io.ReadAll(resp.Body)
 		if err != nil {
-			log.Fatal(err)
+			return TranslatorImage{}, err
 		}
-		defer f.Close()
-
-		var buf bytes.Buffer
-		tee := io.TeeReader(f, &buf)
-
-		img, _, err = image.Decode(tee)
-		size = buf.Len()
+	} else {
+		var err error
+		data, err = os.ReadFile(filepath.ToSlash(file))
 		if err != nil {
-			log.Fatalf("Image decode error: %v", err)
+			return TranslatorImage{}, err
 		}
+	}
+	return Decode(data)
+}
 
-		h = sha256.New()
-		if _, err := io.Copy(h, &buf); err != nil {
-			log.Fatalf("Hash error: %v", err)
-		}
+// Decode decodes the given image file and hashes it.
+func Decode(data []byte) (TranslatorImage, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return TranslatorImage{}, fmt.Errorf("image decode error: %w", err)
 	}
 
-	hashInBytes := h.Sum(nil)
-	hashStr := hex.EncodeToString(hashInBytes)
+	hashInBytes := sha256.Sum256(data)
+	hashStr := hex.EncodeToString(hashInBytes[:])
 	dims := getDimensions(img)
 	imgRGBA := convertToRGBA(img)
 	newImg := TranslatorImage{
 		Image:      imgRGBA,
 		Hash:       hashStr,
 		Dimensions: dims,
-		size:       size,
+		size:       len(data),
 	}
 	log.Debugf("Hash: %v", hashStr)
 	log.Debugf("Image Dimensions: %v", dims)
 	newImg.resize()
-	return newImg
+	newImg.PHash = PerceptualHash(newImg.Image)
+	return newImg, nil
 }
 
 // convertToRGBA converts the given image.Image to *image.RGBA.
@@ -145,6 +128,13 @@ func (img *TranslatorImage) resize() {
 		return
 	}
 
+	// Very tall images are detected in tiles, so each request stays well below the max size.
+	// Shrinking them would only make their text illegible.
+	if detect.NeedsTiling(img.Dimensions.Width, img.Dimensions.Height) {
+		log.Info("Skipping resize for tall image, it will be detected in tiles")
+		return
+	}
+
 	log.Info("Resizing Image")
 	ratio := img.size / desiredSize
 
@@ -190,3 +180,15 @@ func getDimensions(img image.Image) Dimensions {
 		Height: bounds.Max.Y,
 	}
 }
+
+// Thumbnail returns a copy of the given image scaled down to fit within the given max dimension.
+func Thumbnail(img *image.RGBA, maxDim int) *image.RGBA {
+	ratio := GetRatio(getDimensions(img), float32(maxDim))
+	if ratio >= 1 {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, int(float32(b.Dx())*ratio), int(float32(b.Dy())*ratio)))
+	drawX.ApproxBiLinear.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+	return dst
+}
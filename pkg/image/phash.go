@@ -0,0 +1,61 @@
+package image
+
+import (
+	"image"
+	"math/bits"
+)
+
+// minHashBits is how many bits of a perceptual hash must be set, and how many must be clear, for it to be
+// distinctive. Blank and nearly uniform pages have hardly any bits set, so different pages get the same hash.
+var minHashBits = 8
+
+// PerceptualHash returns the difference hash (dHash) of the given image. The image is shrunk to 9x8 gray cells, and
+// each bit is set if a cell is brighter than the cell on its right. Unlike the sha256 hash of the file, it is the same
+// or nearly the same for the same page in another format, quality or size. Returns 0 for images smaller than the cells.
+func PerceptualHash(img *image.RGBA) uint64 {
+	b := img.Bounds()
+	if b.Dx() < 9 || b.Dy() < 8 {
+		return 0
+	}
+
+	var cells [8][9]float64
+	for cy := 0; cy < 8; cy++ {
+		y0, y1 := b.Min.Y+cy*b.Dy()/8, b.Min.Y+(cy+1)*b.Dy()/8
+		for cx := 0; cx < 9; cx++ {
+			x0, x1 := b.Min.X+cx*b.Dx()/9, b.Min.X+(cx+1)*b.Dx()/9
+			// Average brightness of the pixels in the cell.
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := img.PixOffset(x, y)
+					p := img.Pix[i : i+3 : i+3]
+					sum += 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+				}
+			}
+			cells[cy][cx] = sum / float64((x1-x0)*(y1-y0))
+		}
+	}
+
+	var h uint64
+	for cy := 0; cy < 8; cy++ {
+		for cx := 0; cx < 8; cx++ {
+			h <<= 1
+			if cells[cy][cx] > cells[cy][cx+1] {
+				h |= 1
+			}
+		}
+	}
+	return h
+}
+
+// HashDistance returns the number of bits which differ between the given perceptual hashes, from 0 for identical
+// images to 64.
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Distinctive returns if the given perceptual hash can tell its image apart from other images, see minHashBits.
+func Distinctive(h uint64) bool {
+	n := bits.OnesCount64(h)
+	return n >= minHashBits && n <= 64-minHashBits
+}
@@ -0,0 +1,146 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// testPage returns an image with dark panels and text-like stripes on a light background, like a manga page.
+func testPage(w, h int, mirrored bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px := x
+			if mirrored {
+				px = w - 1 - x
+			}
+			// Brightness falls from left to right and changes between the panels.
+			v := 255 - px*180/w
+			if (y*4/h)%2 == 1 && px > w/3 {
+				v = 40 + px*120/w
+			}
+			if (px/(w/12))%3 == 0 && (y/(h/16))%2 == 0 {
+				v = 20
+			}
+			img.Set(x, y, color.RGBA{R: uint8(v), G: uint8(v), B: uint8(v), A: 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	page := testPage(900, 1350, false)
+	hash := PerceptualHash(page)
+	if hash == 0 {
+		t.Fatal("got a blank hash for a page")
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Thumbnail(page, 675), &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		img     *image.RGBA
+		maxDist int // Inclusive.
+		minDist int
+	}{
+		{"same image", page, 0, 0},
+		{"smaller", Thumbnail(page, 450), 4, 0},
+		{"smaller JPEG", decoded.Image, 4, 0},
+		{"other page", testPage(900, 1350, true), 64, 16},
+	}
+	for _, tt := range tests {
+		d := HashDistance(hash, PerceptualHash(tt.img))
+		if d > tt.maxDist || d < tt.minDist {
+			t.Errorf("%s: distance %d, want between %d and %d", tt.name, d, tt.minDist, tt.maxDist)
+		}
+	}
+	if decoded.PHash != PerceptualHash(decoded.Image) {
+		t.Error("Decode did not set the perceptual hash of the image")
+	}
+}
+
+// whitePage returns a white image with a few short lines of dark text-like marks at the given positions, like a page
+// with a single speech bubble and little art.
+func whitePage(w, h int, marks ...image.Point) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for _, m := range marks {
+		for line := 0; line < 3; line++ {
+			for y := m.Y + line*20; y < m.Y+line*20+10; y++ {
+				for x := m.X; x < m.X+60; x++ {
+					img.Set(x, y, color.RGBA{A: 255})
+				}
+			}
+		}
+	}
+	return img
+}
+
+func TestPerceptualHashMostlyWhite(t *testing.T) {
+	a := PerceptualHash(whitePage(900, 1350, image.Pt(100, 100)))
+	b := PerceptualHash(whitePage(900, 1350, image.Pt(700, 1100), image.Pt(400, 600)))
+	// Different pages whose hashes are within the default distance would reuse each other's text.
+	if Distinctive(a) && Distinctive(b) && HashDistance(a, b) <= 4 {
+		t.Errorf("different mostly white pages have distinctive hashes %016x and %016x", a, b)
+	}
+	if Distinctive(PerceptualHash(whitePage(900, 1350))) {
+		t.Error("blank page has a distinctive hash")
+	}
+	if !Distinctive(PerceptualHash(testPage(900, 1350, false))) {
+		t.Error("page with art has no distinctive hash")
+	}
+}
+
+func TestDistinctive(t *testing.T) {
+	for _, tt := range []struct {
+		h    uint64
+		want bool
+	}{
+		{0, false},
+		{0x7f, false},
+		{0xff, true},
+		{0xff00ff00ff00ff00, true},
+		{^uint64(0xff), true},
+		{^uint64(0x7f), false},
+		{^uint64(0), false},
+	} {
+		if got := Distinctive(tt.h); got != tt.want {
+			t.Errorf("Distinctive(%016x) = %v, want %v", tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestPerceptualHashTooSmall(t *testing.T) {
+	if h := PerceptualHash(image.NewRGBA(image.Rect(0, 0, 8, 8))); h != 0 {
+		t.Errorf("got hash %x for an image smaller than the cells, want 0", h)
+	}
+}
+
+func TestHashDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0xff, 0xff, 0},
+		{0, 1, 1},
+		{0xf0, 0x0f, 8},
+		{0, ^uint64(0), 64},
+	} {
+		if got := HashDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HashDistance(%x, %x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	log "github.com/sirupsen/logrus"
+	"image"
+)
+
+// IsSpread returns if the image is wide enough to be a double-page spread.
+// "ratio" is the width to height aspect ratio above which an image is considered a spread.
+func (img TranslatorImage) IsSpread(ratio float32) bool {
+	if img.Dimensions.Height == 0 {
+		return false
+	}
+	return float32(img.Dimensions.Width)/float32(img.Dimensions.Height) > ratio
+}
+
+// SplitSpread splits a double-page spread down the middle and returns both pages in reading order,
+// which is right-to-left if rightToLeft is true and left-to-right otherwise.
+// The hashes of the halves are derived from the hash of the spread, so they are stable across runs.
+func (img TranslatorImage) SplitSpread(rightToLeft bool) []TranslatorImage {
+	b := img.Image.Bounds()
+	mid := b.Min.X + b.Dx()/2
+
+	right := img.half(image.Rect(mid, b.Min.Y, b.Max.X, b.Max.Y), "right")
+	left := img.half(image.Rect(b.Min.X, b.Min.Y, mid, b.Max.Y), "left")
+	log.Debugf("Split spread %v into %v and %v", img.Hash, right.Hash, left.Hash)
+	if !rightToLeft {
+		return []TranslatorImage{left, right}
+	}
+	return []TranslatorImage{right, left}
+}
+
+// half returns the given area of the image as a new TranslatorImage, hashed using the given side name.
+func (img TranslatorImage) half(r image.Rectangle, side string) TranslatorImage {
+	halfImg := convertToRGBA(img.Image.SubImage(r))
+	return TranslatorImage{
+		Image:      halfImg,
+		Hash:       halfHash(img.Hash, side),
+		PHash:      PerceptualHash(halfImg),
+		Dimensions: getDimensions(halfImg),
+		size:       img.size / 2,
+	}
+}
+
+// HalfHashes returns the hashes of both halves of a spread with the given hash, as if it was split with SplitSpread.
+func HalfHashes(hash string) []string {
+	return []string{halfHash(hash, "right"), halfHash(hash, "left")}
+}
+
+// halfHash returns the hash of the given side of a spread with the given hash.
+func halfHash(hash, side string) string {
+	h := sha256.Sum256([]byte(hash + ":" + side))
+	return hex.EncodeToString(h[:])
+}
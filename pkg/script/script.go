@@ -0,0 +1,208 @@
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Formats of the script.
+const (
+	Markdown = "md"
+	Text     = "txt"
+	JSON     = "json"
+)
+
+// Formats are the supported script formats.
+var Formats = []string{Markdown, Text, JSON}
+
+// Page is a page of the chapter with its text blocks.
+type Page struct {
+	Number int    // Page number, starting at 1.
+	Status string // Error or loading status if the page was not translated, blank otherwise.
+	Blocks []detect.TextBlock
+}
+
+// Box is the bounding box of a text block, in pixels of the full size page.
+type Box struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Entry is a text block in the script.
+type Entry struct {
+	Page       int    `json:"page"`
+	Block      int    `json:"block"` // Number of the block in reading order, starting at 1.
+	Box        Box    `json:"box"`
+	Original   string `json:"original"`
+	Translated string `json:"translated"`
+}
+
+// JSONPage is a page in the JSON script.
+type JSONPage struct {
+	Page    int     `json:"page"`
+	Status  string  `json:"status,omitempty"` // Why the page was not translated, blank if it was.
+	Entries []Entry `json:"entries"`
+}
+
+// FormatOf returns the script format for the extension of the given file name, or Markdown if it has none
+// of the supported extensions.
+func FormatOf(name string) string {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	for _, f := range Formats {
+		if ext == f {
+			return f
+		}
+	}
+	return Markdown
+}
+
+// Write writes the text blocks of the given pages to w in the given format, in page and reading order.
+// The blocks of each page are read from right to left if rightToLeft is true, and left to right otherwise.
+func Write(w io.Writer, format string, pages []Page, rightToLeft bool) error {
+	switch format {
+	case Markdown:
+		return writeMarkdown(w, pages, rightToLeft)
+	case Text:
+		return writeText(w, pages, rightToLeft)
+	case JSON:
+		jsonPages := []JSONPage{}
+		for _, pg := range pages {
+			jp := JSONPage{Page: pg.Number, Status: oneLine(pg.Status), Entries: []Entry{}}
+			if pg.Status == "" {
+				jp.Entries = append(jp.Entries, pg.entries(rightToLeft)...)
+			}
+			jsonPages = append(jsonPages, jp)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonPages)
+	default:
+		return fmt.Errorf("unknown script format %q, expected one of: %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// writeMarkdown writes the pages as Markdown, with a heading for each page and each block.
+func writeMarkdown(w io.Writer, pages []Page, rightToLeft bool) error {
+	var b strings.Builder
+	for _, pg := range pages {
+		fmt.Fprintf(&b, "## Page %d\n\n", pg.Number)
+		if pg.Status != "" {
+			fmt.Fprintf(&b, "*Not translated: %s*\n\n", oneLine(pg.Status))
+			continue
+		}
+		for _, e := range pg.entries(rightToLeft) {
+			fmt.Fprintf(&b, "### %d.%d\n\n", e.Page, e.Block)
+			fmt.Fprintf(&b, "Box: %s\n\n", e.Box)
+			fmt.Fprintf(&b, "> %s\n\n", strings.ReplaceAll(e.Original, "\n", "\n> "))
+			fmt.Fprintf(&b, "%s\n\n", e.Translated)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeText writes the pages as plain text, with the original text of each block followed by its translation.
+func writeText(w io.Writer, pages []Page, rightToLeft bool) error {
+	var b strings.Builder
+	for _, pg := range pages {
+		fmt.Fprintf(&b, "=== Page %d ===\n\n", pg.Number)
+		if pg.Status != "" {
+			fmt.Fprintf(&b, "Not translated: %s\n\n", oneLine(pg.Status))
+			continue
+		}
+		for _, e := range pg.entries(rightToLeft) {
+			fmt.Fprintf(&b, "[%d.%d] %s\n", e.Page, e.Block, e.Box)
+			fmt.Fprintf(&b, "Original: %s\n", e.Original)
+			fmt.Fprintf(&b, "Translated: %s\n\n", e.Translated)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String returns the box as "x,y widthxheight".
+func (b Box) String() string {
+	return fmt.Sprintf("%d,%d %dx%d", b.X, b.Y, b.Width, b.Height)
+}
+
+// entries returns the script entries of the page in reading order.
+func (pg Page) entries(rightToLeft bool) []Entry {
+	var entries []Entry
+	for i, block := range readingOrder(pg.Blocks, rightToLeft) {
+		entries = append(entries, Entry{
+			Page:       pg.Number,
+			Block:      i + 1,
+			Box:        boundingBox(block),
+			Original:   block.Text,
+			Translated: block.Translated,
+		})
+	}
+	return entries
+}
+
+// readingOrder returns the given blocks sorted in reading order: rows from top to bottom, and the blocks of each row
+// from right to left if rightToLeft is true. A block starts a new row if its top is below the bottom of the first
+// block of the current row.
+func readingOrder(blocks []detect.TextBlock, rightToLeft bool) []detect.TextBlock {
+	sorted := make([]detect.TextBlock, len(blocks))
+	copy(sorted, blocks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return boundingBox(sorted[i]).Y < boundingBox(sorted[j]).Y
+	})
+
+	var ordered []detect.TextBlock
+	for len(sorted) > 0 {
+		first := boundingBox(sorted[0])
+		n := 1
+		for n < len(sorted) && boundingBox(sorted[n]).Y < first.Y+first.Height {
+			n++
+		}
+		row := sorted[:n]
+		sort.SliceStable(row, func(i, j int) bool {
+			a, b := boundingBox(row[i]), boundingBox(row[j])
+			if rightToLeft {
+				return a.X+a.Width > b.X+b.Width
+			}
+			return a.X < b.X
+		})
+		ordered = append(ordered, row...)
+		sorted = sorted[n:]
+	}
+	return ordered
+}
+
+// boundingBox returns the smallest box which contains all vertices of the block.
+func boundingBox(block detect.TextBlock) Box {
+	if len(block.Vertices) == 0 {
+		return Box{}
+	}
+	minX, minY := block.Vertices[0].X, block.Vertices[0].Y
+	maxX, maxY := minX, minY
+	for _, v := range block.Vertices[1:] {
+		if v.X < minX {
+			minX = v.X
+		}
+		if v.X > maxX {
+			maxX = v.X
+		}
+		if v.Y < minY {
+			minY = v.Y
+		}
+		if v.Y > maxY {
+			maxY = v.Y
+		}
+	}
+	return Box{X: int(minX), Y: int(minY), Width: int(maxX - minX), Height: int(maxY - minY)}
+}
+
+// oneLine replaces the line breaks in the given status with spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,228 @@
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	pb "google.golang.org/genproto/googleapis/cloud/vision/v1"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// block returns a block with the given text whose vertices are the corners of the given box, clockwise from the top left.
+func block(text string, x, y, w, h int32) detect.TextBlock {
+	return detect.TextBlock{
+		Text:       text,
+		Translated: strings.ToUpper(text),
+		Vertices:   []*pb.Vertex{{X: x, Y: y}, {X: x + w, Y: y}, {X: x + w, Y: y + h}, {X: x, Y: y + h}},
+	}
+}
+
+// texts returns the text of the given blocks.
+func texts(blocks []detect.TextBlock) []string {
+	var t []string
+	for _, b := range blocks {
+		t = append(t, b.Text)
+	}
+	return t
+}
+
+func TestReadingOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []detect.TextBlock
+		rtl    []string
+		ltr    []string
+	}{
+		{
+			name:   "one row",
+			blocks: []detect.TextBlock{block("left", 0, 0, 100, 100), block("right", 500, 10, 100, 100), block("middle", 250, 50, 100, 100)},
+			rtl:    []string{"right", "middle", "left"},
+			ltr:    []string{"left", "middle", "right"},
+		},
+		{
+			name: "two rows",
+			blocks: []detect.TextBlock{
+				block("bottom left", 0, 300, 100, 100),
+				block("top left", 0, 0, 100, 100),
+				block("bottom right", 500, 320, 100, 100),
+				block("top right", 500, 20, 100, 100),
+			},
+			rtl: []string{"top right", "top left", "bottom right", "bottom left"},
+			ltr: []string{"top left", "top right", "bottom left", "bottom right"},
+		},
+		{
+			// The row is the height of its first (topmost) block, a block starting below it starts a new row.
+			name:   "row boundary",
+			blocks: []detect.TextBlock{block("first", 0, 0, 100, 100), block("inside", 300, 99, 100, 100), block("below", 600, 100, 100, 100)},
+			rtl:    []string{"inside", "first", "below"},
+			ltr:    []string{"first", "inside", "below"},
+		},
+		{
+			// Blocks of a row are ordered by the edge which is read first.
+			name:   "different widths",
+			blocks: []detect.TextBlock{block("wide", 0, 0, 500, 50), block("narrow", 400, 0, 50, 200)},
+			rtl:    []string{"wide", "narrow"},
+			ltr:    []string{"wide", "narrow"},
+		},
+		{
+			name:   "same position",
+			blocks: []detect.TextBlock{block("a", 0, 0, 100, 100), block("b", 0, 0, 100, 100)},
+			rtl:    []string{"a", "b"},
+			ltr:    []string{"a", "b"},
+		},
+		{
+			name: "no blocks",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := texts(tt.blocks)
+			if got := texts(readingOrder(tt.blocks, true)); !reflect.DeepEqual(got, tt.rtl) {
+				t.Errorf("right to left: got %q, want %q", got, tt.rtl)
+			}
+			if got := texts(readingOrder(tt.blocks, false)); !reflect.DeepEqual(got, tt.ltr) {
+				t.Errorf("left to right: got %q, want %q", got, tt.ltr)
+			}
+			if got := texts(tt.blocks); !reflect.DeepEqual(got, original) {
+				t.Errorf("the given blocks were reordered to %q", got)
+			}
+		})
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		vertices []*pb.Vertex
+		want     Box
+	}{
+		{"rectangle", []*pb.Vertex{{X: 10, Y: 20}, {X: 110, Y: 20}, {X: 110, Y: 80}, {X: 10, Y: 80}}, Box{10, 20, 100, 60}},
+		{"rotated", []*pb.Vertex{{X: 50, Y: 0}, {X: 100, Y: 50}, {X: 50, Y: 100}, {X: 0, Y: 50}}, Box{0, 0, 100, 100}},
+		{"counter-clockwise", []*pb.Vertex{{X: 10, Y: 80}, {X: 110, Y: 80}, {X: 110, Y: 20}, {X: 10, Y: 20}}, Box{10, 20, 100, 60}},
+		{"single vertex", []*pb.Vertex{{X: 5, Y: 7}}, Box{5, 7, 0, 0}},
+		{"no vertices", nil, Box{}},
+	}
+	for _, tt := range tests {
+		if got := boundingBox(detect.TextBlock{Vertices: tt.vertices}); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// testPages returns a translated page with two blocks in one row, and a page which failed.
+func testPages() []Page {
+	return []Page{
+		{Number: 1, Blocks: []detect.TextBlock{block("left", 0, 0, 100, 50), block("right\nline", 200, 0, 100, 50)}},
+		{Number: 2, Status: "Detect failed:\nquota exceeded"},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{Markdown, `## Page 1
+
+### 1.1
+
+Box: 200,0 100x50
+
+> right
+> line
+
+RIGHT
+LINE
+
+### 1.2
+
+Box: 0,0 100x50
+
+> left
+
+LEFT
+
+## Page 2
+
+*Not translated: Detect failed: quota exceeded*
+
+`},
+		{Text, `=== Page 1 ===
+
+[1.1] 200,0 100x50
+Original: right
+line
+Translated: RIGHT
+LINE
+
+[1.2] 0,0 100x50
+Original: left
+Translated: LEFT
+
+=== Page 2 ===
+
+Not translated: Detect failed: quota exceeded
+
+`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, tt.format, testPages(), true); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+
+	if err := Write(&bytes.Buffer{}, "pdf", testPages(), true); err == nil {
+		t.Error("unknown format was written")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, JSON, testPages(), false); err != nil {
+		t.Fatal(err)
+	}
+	var got []JSONPage
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []JSONPage{
+		{Page: 1, Entries: []Entry{
+			{Page: 1, Block: 1, Box: Box{0, 0, 100, 50}, Original: "left", Translated: "LEFT"},
+			{Page: 1, Block: 2, Box: Box{200, 0, 100, 50}, Original: "right\nline", Translated: "RIGHT\nLINE"},
+		}},
+		{Page: 2, Status: "Detect failed: quota exceeded", Entries: []Entry{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Pages without text are listed too, so they can be told apart from failed pages.
+	buf.Reset()
+	if err := Write(&buf, JSON, []Page{{Number: 1}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(strings.Fields(buf.String()), " "); got != `[ { "page": 1, "entries": [] } ]` {
+		t.Errorf("got %s for a page without text", got)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]string{
+		"chapter.md":   Markdown,
+		"chapter.TXT":  Text,
+		"chapter.json": JSON,
+		"chapter.pdf":  Markdown,
+		"chapter":      Markdown,
+		"":             Markdown,
+	} {
+		if got := FormatOf(name); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
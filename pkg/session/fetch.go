@@ -0,0 +1,119 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// Fetch detects and translates the text of the given image with the given config, using the cache when possible.
+// "progress" is called with the status of each step, it may be nil. The config should be validated beforehand.
+func (s *Session) Fetch(ctx context.Context, cfg config.File, img imageW.TranslatorImage, progress func(string)) ([]detect.TextBlock, error) {
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	// See if the block info and translations are already cached.
+	key := cache.Image{Hash: img.Hash, PHash: img.PHash, Width: img.Dimensions.Width, Height: img.Dimensions.Height}
+	service, language := cfg.Translation.SelectedService, cfg.Translation.TargetLanguage
+	// Translations made with another glossary are stale, only their detected text is reused.
+	glossary := cfg.GlossaryDigest()
+	blocks, translateOnly, err := cache.Check(key, service, language, glossary, cfg.Cache.MaxDistance())
+	if err != nil {
+		return nil, err
+	}
+	if blocks != nil && !translateOnly {
+		// Found in cache, we can skip annotation and translation.
+		return blocks, nil
+	}
+
+	// Then in the cache server of the team, if there is one.
+	remote := s.remoteCache(cfg)
+	if remote != nil {
+		progress(`Checking the cache server...`)
+		remoteBlocks, remoteTranslateOnly, err := remote.Check(ctx, key, service, language, glossary, cfg.Cache.MaxDistance())
+		if err != nil {
+			log.Warningf("Cache server unavailable, using the local cache only for %v: %v", remoteBackoff, err)
+			s.remoteFailed(cfg.Cache.Server)
+			remote = nil
+		} else if remoteBlocks != nil && !remoteTranslateOnly {
+			log.Info("Image found in the cache server, skipping API requests.")
+			if err := cache.Add(key, service, language, glossary, remoteBlocks, cfg.Cache.MaxSizeBytes()); err != nil {
+				log.Errorf("Unable to add the image to the cache: %v", err)
+			}
+			return remoteBlocks, nil
+		} else if remoteBlocks != nil && blocks == nil {
+			blocks, translateOnly = remoteBlocks, true
+		}
+	}
+
+	if !translateOnly {
+		progress(`Detecting text...`)
+		// Scan image, get text blocks.
+		blocks, err = s.Detect(ctx, cfg, img.Image)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var allOriginal []string
+	for _, block := range blocks {
+		allOriginal = append(allOriginal, block.Text)
+	}
+
+	progress(`Translating text...`)
+	log.Infof("Translating detected text with: %v", cfg.Translation.SelectedService)
+	allOriginal = cfg.ApplyGlossary(allOriginal)
+	// Translate the text with the service specified in the config.
+	allTranslated, err := s.Translate(ctx, cfg, allOriginal)
+	if err != nil {
+		if len(allTranslated) == 0 {
+			return nil, err
+		}
+		// The translations are the message to show to the user.
+		return nil, errors.New(allTranslated[0])
+	}
+	for i, txt := range allTranslated {
+		blocks[i].Translated = txt
+	}
+	// The translation was already paid for, so it is returned even if it can't be cached.
+	if err := cache.Add(key, service, language, glossary, blocks, cfg.Cache.MaxSizeBytes()); err != nil {
+		log.Errorf("Unable to add the image to the cache: %v", err)
+	}
+	if remote != nil {
+		if err := remote.Add(ctx, key, service, language, glossary, blocks); err != nil {
+			log.Warningf("Unable to add the image to the cache server: %v", err)
+			s.remoteFailed(cfg.Cache.Server)
+		}
+	}
+	return blocks, nil
+}
+
+// remoteCache returns the client of the cache server of the given config, or nil if it has none or it failed less
+// than remoteBackoff ago.
+func (s *Session) remoteCache(cfg config.File) *cache.Remote {
+	if cfg.Cache.Server == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.remoteDown[cfg.Cache.Server]) < remoteBackoff {
+		log.Debugf("Skipping the cache server, it failed less than %v ago", remoteBackoff)
+		return nil
+	}
+	return &cache.Remote{URL: cfg.Cache.Server, Token: cfg.Cache.Token, Client: s.http}
+}
+
+// remoteFailed records that the cache server at the given URL failed, so it is not used for remoteBackoff.
+func (s *Session) remoteFailed(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.remoteDown == nil {
+		s.remoteDown = map[string]time.Time{}
+	}
+	s.remoteDown[url] = time.Now()
+}
@@ -0,0 +1,172 @@
+package session
+
+import (
+	gtranslate "cloud.google.com/go/translate"
+	vision "cloud.google.com/go/vision/apiv1"
+	"context"
+	"errors"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	"github.com/cameronkinsella/manga-translator/pkg/translate"
+	log "github.com/sirupsen/logrus"
+	"image"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var errClosed = errors.New("session is closed")
+
+// Session owns the API clients used to detect and translate text. The clients are created when they are first
+// needed and reused for every page, so their connections are reused, until the session is closed.
+type Session struct {
+	mu     sync.Mutex
+	closed bool
+	ctx    context.Context // Cancelled when the session is closed.
+	cancel context.CancelFunc
+
+	vision      *vision.ImageAnnotatorClient
+	visionCreds string // Service account key the Vision client was created with.
+	google      *gtranslate.Client
+	googleCreds string // API key or service account key the Cloud Translation client was created with.
+	http        *http.Client
+	stale       []io.Closer // Clients which were replaced after the config changed, they may still be in use.
+
+	remoteDown map[string]time.Time // When each cache server last failed, see remoteBackoff.
+}
+
+// remoteBackoff is how long a cache server which failed is not used, so an unreachable server doesn't delay every page
+// by the time it takes for the request to time out.
+var remoteBackoff = time.Minute
+
+// New creates a session without any clients.
+func New() *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Session{
+		ctx:    ctx,
+		cancel: cancel,
+		http:   &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+	}
+}
+
+// Context returns the context of the session, which is cancelled when the session is closed.
+// The contexts given to Detect and Translate should be derived from it.
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Detect detects the text in the given image with the Vision API, using the service account key and the source language
+// of the given config.
+// The request is cancelled when the given context is done. The detect timeout applies to each request, a tall image
+// split into tiles makes one request per tile.
+func (s *Session) Detect(ctx context.Context, cfg config.File, img *image.RGBA) ([]detect.TextBlock, error) {
+	client, err := s.visionClient(cfg.CloudVision.CredentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	return detect.GetTextBlocks(ctx, client, img, cfg.Translation.SourceLanguage, cfg.Timeouts.DetectTimeout())
+}
+
+// Translate translates the given text with the service selected in the given config. If an error is returned,
+// the translations are the error message to show in place of each text. The request is cancelled when the given
+// context is done.
+func (s *Session) Translate(ctx context.Context, cfg config.File, txt []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeouts.TranslateTimeout())
+	defer cancel()
+
+	tl := cfg.Translation
+	switch tl.SelectedService {
+	case "google":
+		client, err := s.googleClient(tl.Google.APIKey, cfg.CloudVision.CredentialsPath)
+		if err != nil {
+			return translate.TranslationError("Translation request failed, ensure that your API key or service account key is correct.", txt), err
+		}
+		return translate.GoogleTranslate(ctx, client, txt, tl.SourceLanguage, tl.TargetLanguage)
+	case "deepL":
+		return translate.DeepLTranslate(ctx, s.http, txt, tl.SourceLanguage, tl.TargetLanguage, tl.DeepL.APIKey)
+	default:
+		return translate.TranslationError(`Your config does not have a valid selected service, press "," to open the settings and select one.`, txt),
+			errors.New("no selected service")
+	}
+}
+
+// Close closes all clients of the session. The session can't be used afterwards.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.cancel()
+	log.Info("Closing API clients")
+
+	closers := s.stale
+	if s.vision != nil {
+		closers = append(closers, s.vision)
+	}
+	if s.google != nil {
+		closers = append(closers, s.google)
+	}
+	var firstErr error
+	for _, c := range closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.http.CloseIdleConnections()
+	return firstErr
+}
+
+// visionClient returns the Vision API client for the given service account key, creating it if necessary.
+func (s *Session) visionClient(credentialsPath string) (*vision.ImageAnnotatorClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errClosed
+	}
+	if s.vision != nil && s.visionCreds == credentialsPath {
+		return s.vision, nil
+	}
+
+	log.Info("Creating Vision API client")
+	client, err := detect.NewClient(context.Background(), credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.vision != nil {
+		s.stale = append(s.stale, s.vision)
+	}
+	s.vision, s.visionCreds = client, credentialsPath
+	return client, nil
+}
+
+// googleClient returns the Cloud Translation client for the given API key or service account key,
+// creating it if necessary.
+func (s *Session) googleClient(apiKey, credentialsPath string) (*gtranslate.Client, error) {
+	creds := apiKey
+	if creds == "" {
+		creds = credentialsPath
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, errClosed
+	}
+	if s.google != nil && s.googleCreds == creds {
+		return s.google, nil
+	}
+
+	log.Info("Creating Cloud Translation client")
+	client, err := translate.NewGoogleClient(context.Background(), apiKey, credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.google != nil {
+		s.stale = append(s.stale, s.google)
+	}
+	s.google, s.googleCreds = client, creds
+	return client, nil
+}
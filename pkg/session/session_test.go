@@ -0,0 +1,71 @@
+package session
+
+import (
+	"context"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mtl-session-test")
+	if err != nil {
+		panic(err)
+	}
+	// The paths are resolved once, so every test uses the same cache.
+	config.SetHome(dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestRemoteBackoff(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var cfg config.File
+	cfg.CloudVision.CredentialsPath = filepath.Join(t.TempDir(), "missing.json")
+	cfg.Translation.SelectedService = "google"
+	cfg.Translation.TargetLanguage = "en"
+	cfg.Cache.Server, cfg.Cache.Token = srv.URL, "secret"
+	img := imageW.TranslatorImage{
+		Image:      image.NewRGBA(image.Rect(0, 0, 10, 10)),
+		Hash:       "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+		Dimensions: imageW.Dimensions{Width: 10, Height: 10},
+	}
+
+	s := New()
+	defer s.Close()
+	// Detecting fails without a service account key, the cache server is asked first.
+	for i := 0; i < 3; i++ {
+		if _, err := s.Fetch(context.Background(), cfg, img, nil); err == nil {
+			t.Fatal("Fetch() without a service account key returned no error")
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("the failed cache server got %d requests, want 1", n)
+	}
+
+	other := cfg
+	other.Cache.Server = srv.URL + "/other"
+	if s.remoteCache(other) == nil {
+		t.Error("another cache server is skipped too")
+	}
+
+	defer func(backoff time.Duration) { remoteBackoff = backoff }(remoteBackoff)
+	remoteBackoff = 0
+	if s.remoteCache(cfg) == nil {
+		t.Error("the cache server is still skipped after the backoff")
+	}
+}
@@ -1,6 +1,7 @@
 package translate
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -34,7 +35,8 @@ func TranslationError(message string, txt []string) []string {
 }
 
 // DeepLTranslate translates the given slice of strings from source language to target language using the DeepL API.
-func DeepLTranslate(txt []string, source, target, apiKey string) ([]string, error) {
+// The given HTTP client should be reused for all requests, so its connections are reused.
+func DeepLTranslate(ctx context.Context, client *http.Client, txt []string, source, target, apiKey string) ([]string, error) {
 	log.WithFields(log.Fields{
 		"sourceLanguage": source,
 		"targetLanguage": target,
@@ -54,13 +56,11 @@ func DeepLTranslate(txt []string, source, target, apiKey string) ([]string, erro
 	if source != "" {
 		params.Add("source_lang", source)
 	}
-	if target == "" {
-		target = "EN-US"
-	}
 	params.Add("target_lang", target)
 	params.Add("model_type", "quality_optimized")
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodPost,
 		baseURL+"translate",
 		strings.NewReader(params.Encode()),
@@ -73,7 +73,6 @@ func DeepLTranslate(txt []string, source, target, apiKey string) ([]string, erro
 	req.Header.Set("Authorization", "DeepL-Auth-Key "+apiKey)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Errorf("client.Do: %v", err)
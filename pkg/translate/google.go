@@ -8,8 +8,26 @@ import (
 	"google.golang.org/api/option"
 )
 
+// NewGoogleClient creates a Cloud Translation API client which authenticates with the given API key,
+// or with the given service account key if the API key is blank.
+// The client should be reused for all requests, and closed when it is no longer needed.
+func NewGoogleClient(ctx context.Context, apiKey, credentialsPath string) (*translate.Client, error) {
+	var opt option.ClientOption
+	if apiKey == "" {
+		opt = option.WithCredentialsFile(credentialsPath)
+	} else {
+		opt = option.WithAPIKey(apiKey)
+	}
+	client, err := translate.NewClient(ctx, opt)
+	if err != nil {
+		log.Errorf("NewClient: %v", err)
+		return nil, err
+	}
+	return client, nil
+}
+
 // GoogleTranslate translates the given slice of strings from source language to target language using the Google Cloud Translation API.
-func GoogleTranslate(txt []string, source, target, apiKey string) ([]string, error) {
+func GoogleTranslate(ctx context.Context, client *translate.Client, txt []string, source, target string) ([]string, error) {
 	log.WithFields(log.Fields{
 		"sourceLanguage": source,
 		"targetLanguage": target,
@@ -29,35 +47,12 @@ func GoogleTranslate(txt []string, source, target, apiKey string) ([]string, err
 		options.Source = sourceLang
 	}
 
-	// Support configs which do not have "targetLanguage" (version <=1.2.0)
-	if target == "" {
-		target = "en"
-	}
-
 	targetLang, err := language.Parse(target)
 	if err != nil {
 		log.Errorf("language.Parse: %v", err)
 		return TranslationError("Invalid target language selected in config.", txt), err
 	}
 
-	ctx := context.Background()
-	var client *translate.Client
-	if apiKey == "" {
-		client, err = translate.NewClient(ctx)
-		if err != nil {
-			log.Errorf("NewClient: %v", err)
-			return TranslationError("Translation request failed, ensure that the absolute path given for your Vision API service account key is correct", txt), err
-		}
-	} else {
-		apiKeyOption := option.WithAPIKey(apiKey)
-		client, err = translate.NewClient(ctx, apiKeyOption)
-		if err != nil {
-			log.Errorf("NewClient: %v", err)
-			return TranslationError("Translation request failed, ensure that your API key is correct.", txt), err
-		}
-	}
-	defer client.Close()
-
 	resp, err := client.Translate(ctx, txt, targetLang, &options)
 	log.Debug(resp)
 
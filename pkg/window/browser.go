@@ -0,0 +1,246 @@
+package window
+
+import (
+	"gioui.org/layout"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// imageExtensions are the file extensions of the image formats which can be decoded.
+var imageExtensions = []string{".png", ".jpg", ".jpeg", ".webp"}
+
+// fileBrowser is a simple file browser used to open images without restarting the application.
+type fileBrowser struct {
+	open   bool
+	dir    string
+	status string // Loading or error status of the last selection.
+
+	entries []os.DirEntry
+	buttons []widget.Clickable
+	list    layout.List
+
+	upBtn      widget.Clickable
+	openDirBtn widget.Clickable
+	closeBtn   widget.Clickable
+	appendBox  widget.Bool // Append the selected images to the current pages instead of replacing them.
+}
+
+// show opens the file browser in the given directory.
+func (b *fileBrowser) show(dir string) {
+	b.open = true
+	b.status = ""
+	b.list.Axis = layout.Vertical
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	b.readDir(dir)
+}
+
+// readDir lists the subdirectories and images in the given directory.
+func (b *fileBrowser) readDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Errorf("ReadDir: %v", err)
+		b.status = err.Error()
+		return
+	}
+	b.dir = dir
+	b.entries = b.entries[:0]
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if e.IsDir() || isImageFile(e.Name()) {
+			b.entries = append(b.entries, e)
+		}
+	}
+	// Directories first.
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return b.entries[i].IsDir() && !b.entries[j].IsDir()
+	})
+	b.buttons = make([]widget.Clickable, len(b.entries))
+	b.list.Position = layout.Position{}
+}
+
+// update handles the clicks in the file browser and returns the image paths selected by the user,
+// and if they should be appended to the current pages.
+func (b *fileBrowser) update() (paths []string, appendPages bool) {
+	if b.closeBtn.Clicked() {
+		b.open = false
+	}
+	if b.upBtn.Clicked() {
+		b.readDir(filepath.Dir(b.dir))
+	}
+	if b.openDirBtn.Clicked() {
+		paths = imagesInDir(b.dir)
+	}
+	for i := range b.buttons {
+		if !b.buttons[i].Clicked() {
+			continue
+		}
+		path := filepath.Join(b.dir, b.entries[i].Name())
+		if b.entries[i].IsDir() {
+			b.readDir(path)
+			break
+		}
+		paths = []string{path}
+	}
+	if len(paths) > 0 {
+		b.status = "Opening..."
+	}
+	return paths, b.appendBox.Value
+}
+
+// layout is the file browser widget.
+func (b *fileBrowser) layout(gtx C, th *material.Theme) D {
+	colorBox(gtx, gtx.Constraints.Max, DarkGray)
+
+	button := func(btn *widget.Clickable, label string) layout.FlexChild {
+		return layout.Rigid(func(gtx C) D {
+			return layout.Inset{Right: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+				bt := material.Button(th, btn, label)
+				bt.Background = Gray
+				return bt.Layout(gtx)
+			})
+		})
+	}
+
+	return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				l := material.H6(th, b.dir)
+				l.Font = text.Font{Typeface: "Noto"}
+				l.Color = LightGray
+				return l.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layout.Inset{Top: unit.Dp(6), Bottom: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						button(&b.upBtn, "Up"),
+						button(&b.openDirBtn, "Open all images here"),
+						button(&b.closeBtn, "Close"),
+						layout.Rigid(func(gtx C) D {
+							cb := material.CheckBox(th, &b.appendBox, "Append to current pages")
+							cb.Color = LightGray
+							cb.IconColor = LightGray
+							return cb.Layout(gtx)
+						}),
+					)
+				})
+			}),
+			layout.Rigid(func(gtx C) D {
+				if b.status == "" {
+					return D{}
+				}
+				l := material.Body2(th, b.status)
+				l.Color = LightGray
+				return l.Layout(gtx)
+			}),
+			layout.Rigid(divider),
+			layout.Flexed(1, func(gtx C) D {
+				return b.list.Layout(gtx, len(b.entries), func(gtx C, i int) D {
+					name := b.entries[i].Name()
+					if b.entries[i].IsDir() {
+						name += string(filepath.Separator)
+					}
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return Clickable(gtx, &b.buttons[i], true, func(gtx C) D {
+						return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+							l := material.Body1(th, name)
+							l.Font = text.Font{Typeface: "Noto"}
+							l.Color = LightGray
+							return l.Layout(gtx)
+						})
+					})
+				})
+			}),
+		)
+	})
+}
+
+// isImageFile returns if the given file name has the extension of a supported image format.
+func isImageFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range imageExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// imagesInDir returns the paths of all images in the given directory, sorted by name.
+func imagesInDir(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Errorf("ReadDir: %v", err)
+		return nil
+	}
+	var paths []string
+	for _, e := range entries {
+		if !e.IsDir() && isImageFile(e.Name()) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	return paths
+}
+
+// parsePaths returns the image paths and URLs in the given text, which is expected to contain one per line.
+// This is the format used by most file managers when files are copied to the clipboard.
+// Directories are replaced by the images they contain.
+func parsePaths(s string) []string {
+	var paths []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if isURL(line) {
+			paths = append(paths, line)
+			continue
+		}
+		if strings.HasPrefix(line, "file://") {
+			u, err := url.Parse(line)
+			if err != nil {
+				continue
+			}
+			line = filepath.FromSlash(u.Path)
+		}
+		info, err := os.Stat(line)
+		if err != nil {
+			log.Debugf("Ignoring pasted text: %q", line)
+			continue
+		}
+		if info.IsDir() {
+			paths = append(paths, imagesInDir(line)...)
+		} else if isImageFile(line) {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// isURL returns if the given path is an HTTP(S) URL.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// ChapterDir returns the directory of the given chapter images, or an empty string if they are not local files.
+func ChapterDir(paths []string) string {
+	if len(paths) == 0 || isURL(paths[0]) {
+		return ""
+	}
+	dir, err := filepath.Abs(filepath.Dir(paths[0]))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
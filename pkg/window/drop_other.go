@@ -0,0 +1,12 @@
+//go:build !windows
+
+package window
+
+import "errors"
+
+// acceptDrops calls the given function with the paths of the files dropped onto the window of the application, and
+// whether Shift was held to append them. It must be called once, after the window was created.
+// Only supported on Windows, Gio doesn't report dropped files on the other platforms.
+func acceptDrops(dropped func(paths []string, appendPages bool)) error {
+	return errors.New("dropping files is not supported on this platform")
+}
@@ -0,0 +1,100 @@
+package window
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32  = syscall.NewLazyDLL("user32.dll")
+	shell32 = syscall.NewLazyDLL("shell32.dll")
+
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procGetClassNameW            = user32.NewProc("GetClassNameW")
+	procSetWindowLongPtrW        = user32.NewProc("SetWindowLongPtrW")
+	procCallWindowProcW          = user32.NewProc("CallWindowProcW")
+	procGetKeyState              = user32.NewProc("GetKeyState")
+	procDragAcceptFiles          = shell32.NewProc("DragAcceptFiles")
+	procDragQueryFileW           = shell32.NewProc("DragQueryFileW")
+	procDragFinish               = shell32.NewProc("DragFinish")
+)
+
+const (
+	wmDropFiles = 0x0233
+	gwlpWndProc = ^uintptr(3) // -4
+	vkShift     = 0x10
+)
+
+// Gio doesn't report files dropped onto the window, so the window procedure of its window is replaced by one which
+// handles WM_DROPFILES and passes all other messages on.
+var (
+	gioWindowProc uintptr
+	onDrop        func(paths []string, appendPages bool)
+)
+
+// acceptDrops calls the given function with the paths of the files dropped onto the window of the application, and
+// whether Shift was held to append them. It must be called once, after the window was created.
+func acceptDrops(dropped func(paths []string, appendPages bool)) error {
+	hwnd := findWindow()
+	if hwnd == 0 {
+		return errors.New("window not found")
+	}
+	onDrop = dropped
+	prev, _, err := procSetWindowLongPtrW.Call(hwnd, gwlpWndProc, syscall.NewCallback(dropWindowProc))
+	if prev == 0 {
+		return err
+	}
+	gioWindowProc = prev
+	procDragAcceptFiles.Call(hwnd, 1)
+	return nil
+}
+
+// findWindow returns the handle of the Gio window of this process, or 0 if there is none.
+func findWindow() uintptr {
+	var found uintptr
+	pid := uint32(os.Getpid())
+	cb := syscall.NewCallback(func(hwnd, _ uintptr) uintptr {
+		var windowPid uint32
+		procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&windowPid)))
+		if windowPid != pid {
+			return 1
+		}
+		class := make([]uint16, 64)
+		n, _, _ := procGetClassNameW.Call(hwnd, uintptr(unsafe.Pointer(&class[0])), uintptr(len(class)))
+		if syscall.UTF16ToString(class[:n]) != "GioWindow" {
+			return 1
+		}
+		found = hwnd
+		return 0 // Stop enumerating.
+	})
+	procEnumWindows.Call(cb, 0)
+	return found
+}
+
+// dropWindowProc is the window procedure of the Gio window, it handles dropped files and passes all other messages to
+// the window procedure of Gio.
+func dropWindowProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	if msg != wmDropFiles {
+		r, _, _ := procCallWindowProcW.Call(gioWindowProc, hwnd, msg, wParam, lParam)
+		return r
+	}
+
+	hDrop := wParam
+	n, _, _ := procDragQueryFileW.Call(hDrop, 0xFFFFFFFF, 0, 0)
+	paths := make([]string, 0, n)
+	for i := uintptr(0); i < n; i++ {
+		size, _, _ := procDragQueryFileW.Call(hDrop, i, 0, 0)
+		buf := make([]uint16, size+1)
+		procDragQueryFileW.Call(hDrop, i, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		paths = append(paths, syscall.UTF16ToString(buf))
+	}
+	procDragFinish.Call(hDrop)
+
+	// The high bit of the state is set while the key is down.
+	state, _, _ := procGetKeyState.Call(vkShift)
+	onDrop(paths, state&0x8000 != 0)
+	return 0
+}
@@ -0,0 +1,134 @@
+package window
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/cameronkinsella/manga-translator/pkg/script"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// exportPrompt exports the text blocks of the loaded pages as a script, in one of the script formats.
+type exportPrompt struct {
+	open   bool
+	status string // Path of the last export, or its error.
+
+	buttons  [3]widget.Clickable // One for each of script.Formats.
+	closeBtn widget.Clickable
+}
+
+// exportLabels are the button labels of script.Formats.
+var exportLabels = [3]string{"Markdown", "Text", "JSON"}
+
+// update handles the clicks in the prompt, and exports the pages of the given chapter in the format which was clicked.
+func (ex *exportPrompt) update(p pageList, paths []string) {
+	if ex.closeBtn.Clicked() {
+		ex.open = false
+	}
+	for i := range ex.buttons {
+		if ex.buttons[i].Clicked() {
+			ex.export(p, scriptPath(paths, script.Formats[i]), script.Formats[i])
+		}
+	}
+}
+
+// export writes the text blocks of the pages to the given file in the given format.
+func (ex *exportPrompt) export(p pageList, path, format string) {
+	pages := make([]script.Page, p.len)
+	missing := 0
+	for i, pg := range p.pages {
+		pages[i] = script.Page{Number: i + 1, Blocks: pg.blocks}
+		if !pg.text.finished || !pg.text.ok {
+			pages[i].Status = pg.text.status
+			if pages[i].Status == "" {
+				pages[i].Status = "Not loaded yet"
+			}
+			missing++
+		}
+	}
+
+	log.Infof("Exporting script to %v", path)
+	f, err := os.Create(path)
+	if err != nil {
+		log.Errorf("Unable to export script: %v", err)
+		ex.status = err.Error()
+		return
+	}
+	defer f.Close()
+	if err := script.Write(f, format, pages, p.rtl); err != nil {
+		log.Errorf("Unable to export script: %v", err)
+		ex.status = err.Error()
+		return
+	}
+	ex.status = "Saved to " + path
+	if missing > 0 {
+		ex.status += fmt.Sprintf(" (%d pages were not translated yet)", missing)
+	}
+}
+
+// scriptPath returns the path of the exported script of the given chapter, which is saved in the chapter's directory,
+// or in the working directory if it is not a local directory.
+func scriptPath(paths []string, format string) string {
+	dir := ChapterDir(paths)
+	name := "chapter"
+	if dir != "" {
+		name = filepath.Base(dir)
+	}
+	return filepath.Join(dir, name+"-script."+format)
+}
+
+// layout is the prompt widget, shown at the bottom of the image.
+func (ex *exportPrompt) layout(gtx C, th *material.Theme) D {
+	button := func(btn *widget.Clickable, label string) layout.FlexChild {
+		return layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+				bt := material.Button(th, btn, label)
+				bt.Background = Gray
+				return bt.Layout(gtx)
+			})
+		})
+	}
+
+	return layout.S.Layout(gtx, func(gtx C) D {
+		return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx C) D {
+					return colorBox(gtx, gtx.Constraints.Min, DarkGray)
+				}),
+				layout.Stacked(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx C) D {
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(func(gtx C) D {
+								return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+									layout.Rigid(func(gtx C) D {
+										l := material.Body1(th, "Export the script as:")
+										l.Color = LightGray
+										return l.Layout(gtx)
+									}),
+									button(&ex.buttons[0], exportLabels[0]),
+									button(&ex.buttons[1], exportLabels[1]),
+									button(&ex.buttons[2], exportLabels[2]),
+									button(&ex.closeBtn, "Close"),
+								)
+							}),
+							layout.Rigid(func(gtx C) D {
+								if ex.status == "" {
+									return D{}
+								}
+								return layout.Inset{Top: unit.Dp(4)}.Layout(gtx, func(gtx C) D {
+									l := material.Body2(th, ex.status)
+									l.Color = LightGray
+									return l.Layout(gtx)
+								})
+							}),
+						)
+					})
+				}),
+			)
+		})
+	})
+}
@@ -1,8 +1,7 @@
 package window
 
 import (
-	"errors"
-	"gioui.org/app"
+	"context"
 	"gioui.org/f32"
 	"gioui.org/layout"
 	"gioui.org/op"
@@ -10,106 +9,43 @@ import (
 	"gioui.org/op/paint"
 	"gioui.org/unit"
 	"gioui.org/widget"
-	"github.com/cameronkinsella/manga-translator/pkg/cache"
 	"github.com/cameronkinsella/manga-translator/pkg/config"
 	"github.com/cameronkinsella/manga-translator/pkg/detect"
 	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
-	"github.com/cameronkinsella/manga-translator/pkg/translate"
-	log "github.com/sirupsen/logrus"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
 	"image"
 	"math"
 )
 
+// statusOK is the status of pages which were detected and translated successfully.
+var statusOK = `Done!`
+
 // textBlocks indicates that status of the text detection and translation process.
 type textBlocks struct {
 	status   string // Loading status.
+	queued   bool   // Is true if the page is waiting for a worker.
 	loading  bool   // Is true if the process is in progress.
 	finished bool   // Is true the process is complete.
 	ok       bool   // Is true the process did not encounter any errors.
 }
 
-// getText performs text detection and translation for the given image and creates text block widgets for each of the text blocks.
-func (t *textBlocks) getText(w *app.Window, cfg *config.File, img imageW.TranslatorImage, blocks *[]detect.TextBlock, blockButtons *[]widget.Clickable) {
-	t.loading = true
-
-	// Signal goroutine death and update frame when finished.
-	defer func() {
-		t.loading = false
-		t.finished = true
-		t.ok = t.status == `Done!`
-		w.Invalidate()
-	}()
-
-	var blankCfg config.File
-
+// fetchText performs text detection and translation for the given image, using the cache when possible.
+// "progress" is called with the status of each step. Returns the text blocks and the final status,
+// which is statusOK if there were no errors.
+func fetchText(ctx context.Context, sess *session.Session, cfg config.File, img imageW.TranslatorImage, progress func(string)) ([]detect.TextBlock, string) {
 	// If the config is blank/doesn't exist, skip all steps and show error message.
-	if *cfg == blankCfg {
-		t.status = `Your config is either blank or doesn't exist, run the "manga-translator-setup" application to create one.`
-		return
+	if cfg.Empty() {
+		return nil, `Your config is either blank or doesn't exist, press "," to open the settings or run the "manga-translator-setup" application to create one.`
+	}
+	if errs := config.Validate(cfg); len(errs) > 0 {
+		return nil, "Your config has problems, press \",\" to open the settings and fix them:\n" + config.FormatErrors(errs)
 	}
-	var translateOnly bool
-	// See if the block info and translations are already cached.
-	*blocks, translateOnly = cache.Check(img.Hash, cfg.Translation.SelectedService)
-
-	if *blocks == nil || translateOnly {
-		var err error
-		if !translateOnly {
-			t.status = `Detecting text...`
-			// Scan image, get text annotation.
-			annotation, err := detect.GetAnnotation(img.Image)
-			if err != nil {
-				*blocks = []detect.TextBlock{}
-				t.status = err.Error()
-				return
-			}
-
-			*blocks = detect.OrganizeAnnotation(annotation)
-		}
-		// For each text block, create a new block button and add its text to allOriginal
-		var allOriginal []string
-		for _, block := range *blocks {
-			*blockButtons = append(*blockButtons, widget.Clickable{})
-			allOriginal = append(allOriginal, block.Text)
-		}
 
-		t.status = `Translating text...`
-		log.Infof("Translating detected text with: %v", cfg.Translation.SelectedService)
-		// Translate the text with the service specified in the config.
-		var allTranslated []string
-		if cfg.Translation.SelectedService == "google" {
-			allTranslated, err = translate.GoogleTranslate(
-				allOriginal,
-				cfg.Translation.SourceLanguage,
-				cfg.Translation.TargetLanguage,
-				cfg.Translation.Google.APIKey,
-			)
-		} else if cfg.Translation.SelectedService == "deepL" {
-			allTranslated, err = translate.DeepLTranslate(
-				allOriginal,
-				cfg.Translation.SourceLanguage,
-				cfg.Translation.TargetLanguage,
-				cfg.Translation.DeepL.APIKey,
-			)
-		} else {
-			t.status = `Your config does not have a valid selected service, run the "manga-translator-setup" application again.`
-			err = errors.New("no selected service")
-		}
-		for i, txt := range allTranslated {
-			(*blocks)[i].Translated = txt
-		}
-		if err == nil {
-			cache.Add(img.Hash, cfg.Translation.SelectedService, *blocks)
-		} else {
-			t.status = allTranslated[0]
-			return
-		}
-	} else {
-		// Found in cache, we can skip annotation and translation.
-		for range *blocks {
-			*blockButtons = append(*blockButtons, widget.Clickable{})
-		}
+	blocks, err := sess.Fetch(ctx, cfg, img, progress)
+	if err != nil {
+		return nil, err.Error()
 	}
-	t.status = `Done!`
+	return blocks, statusOK
 }
 
 // blockBox creates a clickable box around the given text block, and returns the widget in a StackChild.
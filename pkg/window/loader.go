@@ -0,0 +1,167 @@
+package window
+
+import (
+	"container/heap"
+	"context"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// loadJob is a request to detect and translate the text of a page.
+type loadJob struct {
+	page  *page
+	index int // Index of the page in the page list, used to prioritize the pages near the current page.
+	gen   int // Generation of the page when the job was queued.
+	ctx   context.Context
+	cfg   config.File // Copy of the config, so it can be changed while the job is running.
+	img   imageW.TranslatorImage
+}
+
+// loadResult is sent from the workers to the UI goroutine, which is the only goroutine that changes the pages.
+// It is either a progress update or, if done is true, the result of a job.
+type loadResult struct {
+	page      *page
+	gen       int
+	status    string
+	done      bool
+	cancelled bool // The job was cancelled before it completed, the page should be loaded again later.
+	blocks    []detect.TextBlock
+}
+
+// loader is a bounded pool of workers which detect and translate the text of pages.
+type loader struct {
+	sess    *session.Session
+	results chan loadResult
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  jobQueue
+	closed bool
+	done   chan struct{} // Closed when the loader is closed.
+}
+
+// jobQueue is a priority queue of jobs, ordered by the distance of their page from the current page.
+// Pages after the current page come before pages which are the same distance before it.
+type jobQueue struct {
+	jobs    []loadJob
+	current int
+}
+
+func (q *jobQueue) Len() int           { return len(q.jobs) }
+func (q *jobQueue) Less(i, j int) bool { return q.priority(q.jobs[i]) < q.priority(q.jobs[j]) }
+func (q *jobQueue) Swap(i, j int)      { q.jobs[i], q.jobs[j] = q.jobs[j], q.jobs[i] }
+func (q *jobQueue) Push(x interface{}) { q.jobs = append(q.jobs, x.(loadJob)) }
+func (q *jobQueue) Pop() interface{} {
+	job := q.jobs[len(q.jobs)-1]
+	q.jobs = q.jobs[:len(q.jobs)-1]
+	return job
+}
+
+// priority returns the priority of the given job, lower is sooner.
+func (q *jobQueue) priority(job loadJob) int {
+	d := job.index - q.current
+	if d >= 0 {
+		return 2 * d
+	}
+	return -2*d + 1
+}
+
+// newLoader starts a loader with the given number of workers.
+func newLoader(sess *session.Session, workers int) *loader {
+	l := &loader{
+		sess:    sess,
+		results: make(chan loadResult),
+		done:    make(chan struct{}),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	log.Infof("Starting %d page loaders", workers)
+	for i := 0; i < workers; i++ {
+		go l.work()
+	}
+	return l
+}
+
+// add queues the given job.
+func (l *loader) add(job loadJob) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	heap.Push(&l.queue, job)
+	l.cond.Signal()
+}
+
+// setCurrent changes the current page, the queued jobs of the pages nearest to it are run first.
+func (l *loader) setCurrent(idx int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.queue.current != idx {
+		l.queue.current = idx
+		heap.Init(&l.queue)
+	}
+}
+
+// next waits for the next job. Returns false if the loader was closed.
+func (l *loader) next() (loadJob, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.queue.Len() == 0 && !l.closed {
+		l.cond.Wait()
+	}
+	if l.closed {
+		return loadJob{}, false
+	}
+	return heap.Pop(&l.queue).(loadJob), true
+}
+
+// close stops the workers once they finish their current job. The jobs should be cancelled separately.
+func (l *loader) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	close(l.done)
+	l.cond.Broadcast()
+}
+
+// work runs jobs until the loader is closed.
+func (l *loader) work() {
+	for {
+		job, ok := l.next()
+		if !ok {
+			return
+		}
+		l.run(job)
+	}
+}
+
+// run detects and translates the text of the page of the given job, and sends the result to the UI goroutine.
+func (l *loader) run(job loadJob) {
+	send := func(r loadResult) {
+		r.page, r.gen = job.page, job.gen
+		select {
+		case l.results <- r:
+		case <-l.done:
+		}
+	}
+
+	if job.ctx.Err() != nil {
+		send(loadResult{done: true, cancelled: true})
+		return
+	}
+	send(loadResult{status: "Loading..."})
+
+	blocks, status := fetchText(job.ctx, l.sess, job.cfg, job.img, func(status string) {
+		send(loadResult{status: status})
+	})
+	if job.ctx.Err() != nil && status != statusOK {
+		log.Debugf("Loading cancelled: %v", job.img.Hash)
+		send(loadResult{done: true, cancelled: true})
+		return
+	}
+	send(loadResult{done: true, status: status, blocks: blocks})
+}
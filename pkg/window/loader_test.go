@@ -0,0 +1,189 @@
+package window
+
+import (
+	"container/heap"
+	"context"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestJobQueuePriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		current int
+		want    []int
+	}{
+		{"middle", 5, []int{5, 6, 4, 7, 3, 8, 2, 9, 1, 0}},
+		{"first", 0, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"last", 9, []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Queue the jobs before the current page is known, like after opening a chapter.
+			q := &jobQueue{}
+			for _, i := range rand.Perm(10) {
+				heap.Push(q, loadJob{index: i})
+			}
+			q.current = tt.current
+			heap.Init(q)
+
+			for n, want := range tt.want {
+				if got := heap.Pop(q).(loadJob).index; got != want {
+					t.Fatalf("job %d: got page %d, want %d", n, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJobQueuePriorityValues(t *testing.T) {
+	q := &jobQueue{current: 10}
+	for _, tt := range []struct{ index, want int }{
+		{10, 0},
+		{12, 4},  // Ahead by 2: 2d.
+		{8, 5},   // Behind by 2: 2d+1.
+		{11, 2},  // Ahead by 1 comes before behind by 1.
+		{9, 3},   // Behind by 1 comes before ahead by 2.
+		{0, 21},  // Behind by 10.
+		{20, 20}, // Ahead by 10.
+	} {
+		if got := q.priority(loadJob{index: tt.index}); got != tt.want {
+			t.Errorf("priority of page %d: got %d, want %d", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestApplyStaleResult(t *testing.T) {
+	_, cancel := context.WithCancel(context.Background())
+	pg := &page{cancel: cancel, text: textBlocks{queued: true}}
+	stale := pg.gen
+	pg.reset()
+
+	pg.apply(loadResult{page: pg, gen: stale, status: "Loading..."})
+	pg.apply(loadResult{page: pg, gen: stale, done: true, status: statusOK})
+	if pg.text != (textBlocks{}) {
+		t.Fatalf("stale result was applied: %+v", pg.text)
+	}
+
+	pg.cancel = cancel
+	pg.apply(loadResult{page: pg, gen: pg.gen, done: true, status: statusOK})
+	if !pg.text.finished || !pg.text.ok {
+		t.Fatalf("current result was not applied: %+v", pg.text)
+	}
+}
+
+func TestApplyCancelledResult(t *testing.T) {
+	_, cancel := context.WithCancel(context.Background())
+	pg := &page{cancel: cancel, text: textBlocks{loading: true}}
+	gen := pg.gen
+
+	pg.apply(loadResult{page: pg, gen: gen, done: true, cancelled: true})
+	if pg.text != (textBlocks{}) || pg.gen == gen {
+		t.Fatalf("cancelled page was not reset: gen %d, %+v", pg.gen, pg.text)
+	}
+}
+
+// TestRapidNavigation jumps between pages, adds pages and resets them while the workers run, the way a reader holding
+// down an arrow key does. Run it with -race.
+func TestRapidNavigation(t *testing.T) {
+	before := runtime.NumGoroutine()
+	sess := session.New()
+	defer sess.Close()
+
+	// Jobs finish without network requests, since the config is invalid.
+	cfg := config.File{Preload: config.Preload{CancelOutside: true, Ahead: 3, Behind: 2}}
+	p := pageList{loader: newLoader(sess, 3)}
+	p.add(make([]imageW.TranslatorImage, 20))
+
+	drain := func() {
+		for {
+			select {
+			case r := <-p.loader.results:
+				r.page.apply(r)
+			default:
+				return
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		switch n := rng.Intn(20); {
+		case n == 0:
+			p.add(make([]imageW.TranslatorImage, 2))
+		case n == 1:
+			p.reload(&cfg)
+		case n < 10:
+			p.jump(rng.Intn(p.len), &cfg)
+		default:
+			// Step forward or back, like the arrow keys.
+			idx := p.idx + 1 - 2*rng.Intn(2)
+			if idx >= 0 && idx < p.len {
+				p.jump(idx, &cfg)
+			}
+		}
+		drain()
+	}
+
+	// The pages around the last page are finished eventually.
+	deadline := time.After(10 * time.Second)
+	for !p.pages[p.idx].text.finished {
+		select {
+		case r := <-p.loader.results:
+			r.page.apply(r)
+		case <-deadline:
+			t.Fatalf("current page %d was not loaded: %+v", p.idx, p.pages[p.idx].text)
+		}
+	}
+	for i, pg := range p.pages {
+		if pg.text.finished && pg.text.ok {
+			t.Errorf("page %d was translated with an invalid config", i)
+		}
+	}
+
+	for _, pg := range p.pages {
+		pg.reset()
+	}
+	p.loader.close()
+	p.loader.close() // Closing twice is allowed.
+	sess.Close()
+
+	for start := time.Now(); runtime.NumGoroutine() > before; {
+		if time.Since(start) > 5*time.Second {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJumpSpread(t *testing.T) {
+	sess := session.New()
+	defer sess.Close()
+	cfg := config.File{Preload: config.Preload{Ahead: -1, Behind: -1}}
+	p := pageList{loader: newLoader(sess, 1)}
+	defer p.loader.close()
+	p.add(make([]imageW.TranslatorImage, 5))
+
+	p.jump(3, &cfg)
+	if p.idx != 3 {
+		t.Errorf("got page %d in single page view, want 3", p.idx)
+	}
+	// Enabling spread view on the second page of a spread moves to its first page, like the S key.
+	p.spread = true
+	p.jump(p.idx, &cfg)
+	if p.idx != 2 {
+		t.Errorf("got page %d after enabling spread view, want 2", p.idx)
+	}
+	for idx, want := range map[int]int{0: 0, 1: 0, 4: 4} {
+		p.jump(idx, &cfg)
+		if p.idx != want {
+			t.Errorf("jump(%d) moved to page %d in spread view, want %d", idx, p.idx, want)
+		}
+	}
+}
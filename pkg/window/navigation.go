@@ -0,0 +1,153 @@
+package window
+
+import (
+	"fmt"
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"image"
+	"image/color"
+)
+
+var thumbnailSize = 120 // hard-coded
+
+// pageStatus is the loading status of a page, shown as a badge on its thumbnail.
+type pageStatus int
+
+const (
+	statusPending pageStatus = iota
+	statusQueued
+	statusLoading
+	statusDone
+	statusError
+)
+
+// Badge colors
+var statusColors = map[pageStatus]color.NRGBA{
+	statusPending: Gray,
+	statusQueued:  {R: 0x50, G: 0x90, B: 0xE0, A: 0xFF},
+	statusLoading: {R: 0xF0, G: 0xC0, B: 0x20, A: 0xFF},
+	statusDone:    {R: 0x30, G: 0xC0, B: 0x50, A: 0xFF},
+	statusError:   {R: 0xE0, G: 0x40, B: 0x40, A: 0xFF},
+}
+
+// pageStatus returns the loading status of the text blocks.
+func (t textBlocks) pageStatus() pageStatus {
+	switch {
+	case t.finished && t.ok:
+		return statusDone
+	case t.finished:
+		return statusError
+	case t.loading:
+		return statusLoading
+	case t.queued:
+		return statusQueued
+	default:
+		return statusPending
+	}
+}
+
+// thumbnail returns the thumbnail of the page, creating it the first time it is needed.
+func (pg *page) thumbnail() paint.ImageOp {
+	if pg.thumb.Size() == (image.Point{}) {
+		pg.thumb = paint.NewImageOp(imageW.Thumbnail(pg.image.Image, thumbnailSize))
+	}
+	return pg.thumb
+}
+
+// thumbnailStrip is a horizontal, scrollable list of page thumbnails, each with a status badge.
+func thumbnailStrip(gtx C, th *material.Theme, p pageList, list *layout.List) D {
+	current := map[int]bool{}
+	for _, i := range p.visible() {
+		current[i] = true
+	}
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			return colorBox(gtx, gtx.Constraints.Min, Gray)
+		}),
+		layout.Stacked(func(gtx C) D {
+			return list.Layout(gtx, p.len, func(gtx C, i int) D {
+				return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+					return thumbnailWidget(gtx, th, p.pages[i], i, current[i])
+				})
+			})
+		}),
+	)
+}
+
+// thumbnailWidget is a clickable page thumbnail with its page number and status badge.
+// "current" specifies if the page is currently shown, in which case it is highlighted.
+func thumbnailWidget(gtx C, th *material.Theme, pg *page, idx int, current bool) D {
+	size := gtx.Px(unit.Dp(float32(thumbnailSize)))
+	badge := gtx.Px(unit.Dp(12))
+
+	return Clickable(gtx, &pg.thumbButton, true, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				gtx.Constraints = layout.Exact(image.Pt(size, size))
+				return layout.Stack{}.Layout(gtx,
+					layout.Expanded(func(gtx C) D {
+						if !current {
+							return D{Size: gtx.Constraints.Min}
+						}
+						return widget.Border{
+							Color: LightGray,
+							Width: unit.Dp(2),
+						}.Layout(gtx, func(gtx C) D {
+							return D{Size: gtx.Constraints.Min}
+						})
+					}),
+					layout.Stacked(func(gtx C) D {
+						return widget.Image{
+							Fit:      widget.Contain,
+							Position: layout.Center,
+							Src:      pg.thumbnail(),
+						}.Layout(gtx)
+					}),
+					// Status badge
+					layout.Stacked(func(gtx C) D {
+						rect := f32.Rectangle{Max: f32.Pt(float32(badge), float32(badge))}
+						area := clip.UniformRRect(rect, float32(badge)/2).Push(gtx.Ops)
+						paint.ColorOp{Color: statusColors[pg.text.pageStatus()]}.Add(gtx.Ops)
+						paint.PaintOp{}.Add(gtx.Ops)
+						area.Pop()
+						return D{Size: image.Pt(badge, badge)}
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				l := material.Body2(th, fmt.Sprintf("%d", idx+1))
+				l.Color = LightGray
+				return l.Layout(gtx)
+			}),
+		)
+	})
+}
+
+// gotoWidget is the input box used to jump to a page number.
+func gotoWidget(gtx C, th *material.Theme, editor *widget.Editor, pages int) D {
+	return layout.NE.Layout(gtx, func(gtx C) D {
+		return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx C) D {
+					return colorBox(gtx, gtx.Constraints.Min, DarkGray)
+				}),
+				layout.Stacked(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx C) D {
+						gtx.Constraints.Min.X = gtx.Px(unit.Dp(160))
+						e := material.Editor(th, editor, fmt.Sprintf("Go to page (1-%d)", pages))
+						e.Color = LightGray
+						e.HintColor = Gray
+						return e.Layout(gtx)
+					})
+				}),
+			)
+		})
+	})
+}
@@ -0,0 +1,79 @@
+package window
+
+import (
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// profilePicker is the list of config profiles which the session can switch to.
+type profilePicker struct {
+	open    bool
+	names   []string // Profile names, starting with config.DefaultProfile for the top-level fields.
+	buttons []widget.Clickable
+	list    layout.List
+}
+
+// show opens the profile picker with the profiles of the config file.
+func (pp *profilePicker) show() {
+	var file config.File
+	if err := config.Read(config.Path(), &file); err != nil {
+		// Only the top-level fields can be picked until the file is fixed.
+		log.Errorf("Unable to list the profiles: %v", err)
+	}
+
+	pp.open = true
+	pp.list.Axis = layout.Vertical
+	pp.names = append([]string{config.DefaultProfile}, file.ProfileNames()...)
+	pp.buttons = make([]widget.Clickable, len(pp.names))
+}
+
+// update handles the clicks in the profile picker. If a profile was picked, its name is returned with picked set to true.
+func (pp *profilePicker) update() (name string, picked bool) {
+	for i := range pp.buttons {
+		if pp.buttons[i].Clicked() {
+			pp.open = false
+			return pp.names[i], true
+		}
+	}
+	return "", false
+}
+
+// layout is the profile picker widget. The given profile is highlighted as the one in use.
+func (pp *profilePicker) layout(gtx C, th *material.Theme, current string) D {
+	colorBox(gtx, gtx.Constraints.Max, DarkGray)
+	if current == "" {
+		current = config.DefaultProfile
+	}
+
+	return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				l := material.H4(th, "Profiles")
+				l.Color = LightGray
+				return l.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx C) D {
+				l := material.Body2(th, `Profiles are set in the "profiles" section of mtl-config.yml. Press Escape to cancel.`)
+				l.Color = Gray
+				return l.Layout(gtx)
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				return pp.list.Layout(gtx, len(pp.names), func(gtx C, i int) D {
+					return layout.Inset{Top: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+						gtx.Constraints.Min.X = gtx.Constraints.Max.X
+						bt := material.Button(th, &pp.buttons[i], pp.names[i])
+						bt.Background = DarkGray
+						if pp.names[i] == current {
+							bt.Background = Gray
+						}
+						return bt.Layout(gtx)
+					})
+				})
+			}),
+		)
+	})
+}
@@ -0,0 +1,82 @@
+package window
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// reloadPrompt asks if the pages which were already translated should be translated again after the config changed.
+// If the changed config can't be used, the problem is shown instead.
+type reloadPrompt struct {
+	open  bool
+	pages int   // Number of pages which were already translated.
+	err   error // Problem with the changed config, which was not applied.
+
+	yesBtn widget.Clickable
+	noBtn  widget.Clickable
+}
+
+// update handles the clicks in the prompt. Returns true if the pages should be translated again.
+func (r *reloadPrompt) update() bool {
+	if r.noBtn.Clicked() {
+		r.open = false
+	}
+	if r.yesBtn.Clicked() {
+		r.open = false
+		return true
+	}
+	return false
+}
+
+// layout is the prompt widget, shown at the bottom of the image.
+func (r *reloadPrompt) layout(gtx C, th *material.Theme) D {
+	return layout.S.Layout(gtx, func(gtx C) D {
+		return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+			return layout.Stack{}.Layout(gtx,
+				layout.Expanded(func(gtx C) D {
+					return colorBox(gtx, gtx.Constraints.Min, DarkGray)
+				}),
+				layout.Stacked(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx C) D {
+						return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+							layout.Flexed(1, func(gtx C) D {
+								if r.err != nil {
+									l := material.Body1(th, fmt.Sprintf("The changed config was not applied: %v", r.err))
+									l.Color = errorRed
+									return l.Layout(gtx)
+								}
+								l := material.Body1(th, fmt.Sprintf("The config changed. Translate the %d loaded pages again?", r.pages))
+								l.Color = LightGray
+								return l.Layout(gtx)
+							}),
+							layout.Rigid(func(gtx C) D {
+								if r.err != nil {
+									return D{}
+								}
+								return layout.Inset{Left: unit.Dp(8), Right: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+									bt := material.Button(th, &r.yesBtn, "Translate again")
+									bt.Background = Gray
+									return bt.Layout(gtx)
+								})
+							}),
+							layout.Rigid(func(gtx C) D {
+								txt := "Keep"
+								if r.err != nil {
+									txt = "Dismiss"
+								}
+								return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+									bt := material.Button(th, &r.noBtn, txt)
+									bt.Background = Gray
+									return bt.Layout(gtx)
+								})
+							}),
+						)
+					})
+				}),
+			)
+		})
+	})
+}
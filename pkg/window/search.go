@@ -0,0 +1,281 @@
+package window
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	log "github.com/sirupsen/logrus"
+	"image"
+	"sort"
+	"strings"
+)
+
+var searchThumbnailSize = 48 // hard-coded
+
+// searchHit is a text block which contains the searched text.
+type searchHit struct {
+	page  int    // Index of the page which contains the block, -1 if the image is not open.
+	hash  string // Hash of the image which contains the block.
+	block detect.TextBlock
+}
+
+// cacheSearch is the result of searching the cache in the background.
+type cacheSearch struct {
+	seq     int // Number of the search, results of earlier searches are dropped.
+	matches []cache.Match
+	err     error
+}
+
+// searchPanel searches the text of the loaded pages, and optionally the whole cache.
+type searchPanel struct {
+	open   bool
+	status string // Number of hits or error of the last search.
+
+	query     string
+	seq       int              // Number of the last search.
+	searching bool             // The cache is being searched for the last query.
+	matches   []cache.Match    // Matches in the cache of the last query.
+	cacheErr  error            // Error of searching the cache for the last query.
+	results   chan cacheSearch // The cache is searched in the background, and the matches are sent through this channel.
+
+	editor   widget.Editor
+	cacheBox widget.Bool // Also search the cache, including images which are not open.
+	closeBtn widget.Clickable
+
+	hits    []searchHit
+	buttons []widget.Clickable
+	list    layout.List
+}
+
+// show opens the search panel, keeping the results of the previous search.
+func (s *searchPanel) show() {
+	s.open = true
+	s.editor.SingleLine = true
+	s.editor.Submit = true
+	s.editor.Focus()
+	s.list.Axis = layout.Vertical
+	if s.results == nil {
+		s.results = make(chan cacheSearch)
+	}
+}
+
+// update handles the input in the search panel. If a hit on an open page was picked, it is returned with picked set
+// to true and the panel is closed.
+func (s *searchPanel) update(p pageList) (hit searchHit, picked bool) {
+	if s.closeBtn.Clicked() {
+		s.open = false
+	}
+	for _, ev := range s.editor.Events() {
+		if _, ok := ev.(widget.SubmitEvent); ok {
+			s.search(p)
+		}
+	}
+	if s.cacheBox.Changed() {
+		s.search(p)
+	}
+	for i := range s.buttons {
+		if s.buttons[i].Clicked() && s.hits[i].page >= 0 {
+			s.open = false
+			return s.hits[i], true
+		}
+	}
+	return searchHit{}, false
+}
+
+// search finds the text blocks which contain the text in the search box, in page order. The open pages are searched
+// right away, the cache is searched in the background and its matches are added by searched.
+func (s *searchPanel) search(p pageList) {
+	s.seq++
+	s.query = strings.TrimSpace(s.editor.Text())
+	s.searching = s.query != "" && s.cacheBox.Value
+	s.matches, s.cacheErr = nil, nil
+	s.list.Position = layout.Position{}
+	if s.query == "" {
+		s.hits = s.hits[:0]
+		s.status = ""
+		s.buttons = nil
+		return
+	}
+	log.Debugf("Searching for %q", s.query)
+
+	if s.searching {
+		go searchCache(s.query, s.seq, s.results)
+	}
+	s.collect(p)
+}
+
+// searchCache searches the cache for the given query and sends the matches to the given channel.
+func searchCache(query string, seq int, results chan<- cacheSearch) {
+	matches, err := cache.Search(query)
+	results <- cacheSearch{seq: seq, matches: matches, err: err}
+}
+
+// searched adds the matches of a search of the cache to the hits, unless another search was started since.
+func (s *searchPanel) searched(r cacheSearch, p pageList) {
+	if r.seq != s.seq {
+		return
+	}
+	if r.err != nil {
+		log.Errorf("Cache search failed: %v", r.err)
+	}
+	s.searching = false
+	s.matches, s.cacheErr = r.matches, r.err
+	s.collect(p)
+}
+
+// collect sets the hits to the blocks of the open pages which contain the query, followed by the matches in the
+// cache of the images which are not open or not translated yet.
+func (s *searchPanel) collect(p pageList) {
+	s.hits = s.hits[:0]
+	pages := map[string]int{} // Pages by the hash of their image.
+	for i, pg := range p.pages {
+		pages[pg.image.Hash] = i
+		if !pg.text.finished {
+			continue
+		}
+		for _, b := range pg.blocks {
+			if b.Contains(s.query) {
+				s.hits = append(s.hits, searchHit{page: i, hash: pg.image.Hash, block: b})
+			}
+		}
+	}
+
+	if s.cacheBox.Value {
+		for _, m := range s.matches {
+			i, ok := pages[m.Hash]
+			if !ok {
+				i = -1
+			} else if p.pages[i].text.finished {
+				// Already searched above.
+				continue
+			}
+			s.hits = append(s.hits, searchHit{page: i, hash: m.Hash, block: m.Block})
+		}
+	}
+
+	// Hits on open pages first, in page order.
+	sort.SliceStable(s.hits, func(i, j int) bool {
+		if s.hits[i].page < 0 || s.hits[j].page < 0 {
+			return s.hits[j].page < 0 && s.hits[i].page >= 0
+		}
+		return s.hits[i].page < s.hits[j].page
+	})
+	s.buttons = make([]widget.Clickable, len(s.hits))
+	s.status = fmt.Sprintf("%d results", len(s.hits))
+	if s.searching {
+		s.status += ", searching the cache…"
+	} else if s.cacheErr != nil {
+		s.status += fmt.Sprintf(", the cache could not be searched: %v", s.cacheErr)
+	}
+}
+
+// layout is the search panel widget.
+func (s *searchPanel) layout(gtx C, th *material.Theme, p pageList) D {
+	colorBox(gtx, gtx.Constraints.Max, DarkGray)
+
+	return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				l := material.H4(th, "Search")
+				l.Color = LightGray
+				return l.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layout.Inset{Top: unit.Dp(6), Bottom: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+					return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+						layout.Flexed(1, func(gtx C) D {
+							e := material.Editor(th, &s.editor, "Original or translated text, press Enter to search")
+							e.Font = text.Font{Typeface: "Noto"}
+							e.Color = LightGray
+							e.HintColor = Gray
+							return e.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx C) D {
+							return layout.Inset{Left: unit.Dp(6), Right: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+								cb := material.CheckBox(th, &s.cacheBox, "Search the whole cache")
+								cb.Color = LightGray
+								cb.IconColor = LightGray
+								return cb.Layout(gtx)
+							})
+						}),
+						layout.Rigid(func(gtx C) D {
+							bt := material.Button(th, &s.closeBtn, "Close")
+							bt.Background = Gray
+							return bt.Layout(gtx)
+						}),
+					)
+				})
+			}),
+			layout.Rigid(func(gtx C) D {
+				if s.status == "" {
+					return D{}
+				}
+				l := material.Body2(th, s.status)
+				l.Color = LightGray
+				return l.Layout(gtx)
+			}),
+			layout.Rigid(divider),
+			layout.Flexed(1, func(gtx C) D {
+				return s.list.Layout(gtx, len(s.hits), func(gtx C, i int) D {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					return Clickable(gtx, &s.buttons[i], s.hits[i].page >= 0, func(gtx C) D {
+						return layout.UniformInset(unit.Dp(4)).Layout(gtx, func(gtx C) D {
+							return searchHitWidget(gtx, th, p, s.hits[i])
+						})
+					})
+				})
+			}),
+		)
+	})
+}
+
+// searchHitWidget is a search result with the thumbnail and number of its page, and the text of the block.
+func searchHitWidget(gtx C, th *material.Theme, p pageList, hit searchHit) D {
+	size := gtx.Px(unit.Dp(float32(searchThumbnailSize)))
+	label := "Not open"
+	if hit.page >= 0 {
+		label = fmt.Sprintf("Page %d", hit.page+1)
+	}
+
+	return layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			gtx.Constraints = layout.Exact(image.Pt(size, size))
+			if hit.page < 0 {
+				return D{Size: gtx.Constraints.Min}
+			}
+			return widget.Image{
+				Fit:      widget.Contain,
+				Position: layout.Center,
+				Src:      p.pages[hit.page].thumbnail(),
+			}.Layout(gtx)
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						l := material.Body2(th, label)
+						l.Color = Gray
+						return l.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						l := material.Body1(th, hit.block.Text)
+						l.Font = text.Font{Typeface: "Noto"}
+						l.Color = LightGray
+						return l.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						l := material.Body1(th, hit.block.Translated)
+						l.Font = text.Font{Typeface: "Noto"}
+						l.Color = LightGray
+						return l.Layout(gtx)
+					}),
+				)
+			})
+		}),
+	)
+}
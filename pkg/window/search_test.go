@@ -0,0 +1,46 @@
+package window
+
+import (
+	"github.com/cameronkinsella/manga-translator/pkg/cache"
+	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"testing"
+)
+
+func TestSearched(t *testing.T) {
+	p := pageList{pages: []*page{
+		{image: imageW.TranslatorImage{Hash: "open"}, blocks: []detect.TextBlock{{Text: "ルフィ", Translated: "Luffy"}}, text: textBlocks{finished: true}},
+		{image: imageW.TranslatorImage{Hash: "loading"}},
+	}}
+	s := searchPanel{query: "luffy", seq: 2, searching: true}
+	s.cacheBox.Value = true
+	s.collect(p)
+	if len(s.hits) != 1 || s.status != "1 results, searching the cache…" {
+		t.Fatalf("got hits %v, status %q before the cache was searched, want the open page", s.hits, s.status)
+	}
+
+	matches := []cache.Match{
+		{Hash: "open", Block: detect.TextBlock{Text: "ルフィ", Translated: "Luffy"}},
+		{Hash: "loading", Block: detect.TextBlock{Text: "ルフィ", Translated: "Luffy!"}},
+		{Hash: "closed", Block: detect.TextBlock{Text: "ルフィ", Translated: "Luffy?"}},
+	}
+	s.searched(cacheSearch{seq: 1, matches: matches}, p)
+	if len(s.hits) != 1 || !s.searching {
+		t.Errorf("got hits %v from an earlier search, want them to be dropped", s.hits)
+	}
+
+	s.searched(cacheSearch{seq: 2, matches: matches}, p)
+	want := []searchHit{
+		{page: 0, hash: "open", block: matches[0].Block},
+		{page: 1, hash: "loading", block: matches[1].Block},
+		{page: -1, hash: "closed", block: matches[2].Block},
+	}
+	if len(s.hits) != len(want) || s.searching || s.status != "3 results" {
+		t.Fatalf("got hits %v, status %q, want %v", s.hits, s.status, want)
+	}
+	for i := range want {
+		if s.hits[i].page != want[i].page || s.hits[i].hash != want[i].hash || s.hits[i].block.Translated != want[i].block.Translated {
+			t.Errorf("hit %d: got %v, want %v", i, s.hits[i], want[i])
+		}
+	}
+}
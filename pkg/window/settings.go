@@ -0,0 +1,312 @@
+package window
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/cameronkinsella/manga-translator/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"image/color"
+	"sort"
+	"strings"
+)
+
+var errorRed = color.NRGBA{R: 0xFF, G: 0x60, B: 0x60, A: 0xFF}
+
+// settingsFields are the paths of the config fields which can be edited in the settings panel.
+var settingsFields = map[string]bool{
+	"cloudVision.credentialsPath": true,
+	"translation.selectedService": true,
+	"translation.sourceLanguage":  true,
+	"translation.targetLanguage":  true,
+	"translation.google.apiKey":   true,
+	"translation.deepL.apiKey":    true,
+}
+
+// settingsPanel is the in-app editor for the top-level fields of the config file.
+type settingsPanel struct {
+	open bool
+
+	file    config.File    // Config file being edited, so the fields which are not shown are saved unchanged.
+	profile string         // Profile chosen for the session.
+	series  *config.Series // Series file of the open chapter.
+	inUse   config.File    // Config in use, with the profile, series file and environment variables applied.
+
+	credentials widget.Editor
+	service     widget.Enum
+	source      widget.Editor
+	target      widget.Editor
+	googleKey   widget.Editor
+	deepLKey    widget.Editor
+
+	saveBtn   widget.Clickable
+	cancelBtn widget.Clickable
+	list      layout.List
+
+	errs    map[string]string // Validation errors, keyed by field path.
+	readErr error             // The config file can't be read, so it can't be saved without losing its other fields.
+}
+
+// show opens the settings panel with the fields of the config file, for the session using the given profile
+// and series file. Their overrides are only shown in the settings in use, so they are never saved to the file.
+func (s *settingsPanel) show(profile string, series *config.Series, inUse config.File) {
+	var cfg config.File
+	s.readErr = config.Read(config.Path(), &cfg)
+
+	s.file = cfg
+	s.profile = profile
+	s.series = series
+	s.inUse = inUse
+	s.open = true
+	s.errs = nil
+	if s.readErr != nil {
+		s.setErrors(nil)
+	}
+	s.list.Axis = layout.Vertical
+	for _, e := range []*widget.Editor{&s.credentials, &s.source, &s.target, &s.googleKey, &s.deepLKey} {
+		e.SingleLine = true
+	}
+	s.credentials.SetText(cfg.CloudVision.CredentialsPath)
+	s.service.Value = cfg.Translation.SelectedService
+	s.source.SetText(cfg.Translation.SourceLanguage)
+	s.target.SetText(cfg.Translation.TargetLanguage)
+	s.googleKey.SetText(cfg.Translation.Google.APIKey)
+	s.deepLKey.SetText(cfg.Translation.DeepL.APIKey)
+}
+
+// config returns the config described by the fields of the settings panel.
+func (s *settingsPanel) config() config.File {
+	cfg := s.file
+	cfg.CloudVision.CredentialsPath = strings.TrimSpace(s.credentials.Text())
+	cfg.Translation.SelectedService = s.service.Value
+	cfg.Translation.SourceLanguage = strings.TrimSpace(s.source.Text())
+	cfg.Translation.TargetLanguage = strings.TrimSpace(s.target.Text())
+	cfg.Translation.Google.APIKey = strings.TrimSpace(s.googleKey.Text())
+	cfg.Translation.DeepL.APIKey = strings.TrimSpace(s.deepLKey.Text())
+	return cfg
+}
+
+// update handles the clicks in the settings panel. If the config file was saved, it is returned with saved set to true.
+// The saved config is checked with the profile applied, since that is the config which will be used.
+func (s *settingsPanel) update() (cfg config.File, saved bool) {
+	if s.cancelBtn.Clicked() {
+		s.open = false
+	}
+	if !s.saveBtn.Clicked() {
+		return cfg, false
+	}
+
+	if s.readErr != nil {
+		// Check again, the file may have been fixed since the panel was opened.
+		var file config.File
+		if s.readErr = config.Read(config.Path(), &file); s.readErr != nil {
+			s.setErrors(nil)
+			return cfg, false
+		}
+		s.file = file
+	}
+
+	cfg = s.config()
+	effective, err := config.Resolve(cfg, s.profile, s.series)
+	if err != nil {
+		s.setErrors([]config.FieldError{{Path: "profile", Message: err.Error()}})
+		return cfg, false
+	}
+	if errs := config.Validate(effective); len(errs) > 0 {
+		s.setErrors(errs)
+		return cfg, false
+	}
+
+	if err := config.SaveConfig(cfg); err != nil {
+		// E.g. a read-only config directory or a full disk. The panel stays open, so the changes are not lost.
+		log.Error(err)
+		s.setErrors([]config.FieldError{{Path: "mtl-config.yml", Message: err.Error()}})
+		return cfg, false
+	}
+	log.Info("Config saved from settings panel")
+	s.open = false
+	return cfg, true
+}
+
+// inUseText describes the config in use, including the settings of the profile and series file.
+// API keys are not shown.
+func (s *settingsPanel) inUseText() string {
+	cfg := s.inUse
+	orNone := func(v string) string {
+		if v == "" {
+			return "none"
+		}
+		return v
+	}
+	direction := "right to left"
+	if !cfg.RightToLeft() {
+		direction = "left to right"
+	}
+	lines := []string{
+		"Profile: " + orNone(cfg.Profile),
+		"Series file: " + orNone(cfg.Series),
+		"Translation service: " + orNone(cfg.Translation.SelectedService),
+		"Source language: " + orNone(cfg.Translation.SourceLanguage),
+		"Target language: " + orNone(cfg.Translation.TargetLanguage),
+		"Reading direction: " + direction,
+		fmt.Sprintf("Glossary: %d terms", len(cfg.Glossary)),
+	}
+	terms := make([]string, 0, len(cfg.Glossary))
+	for term := range cfg.Glossary {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	for _, term := range terms {
+		lines = append(lines, fmt.Sprintf("    %s → %s", term, cfg.Glossary[term]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// setErrors shows the given validation errors next to their fields, and the problem reading the config file.
+func (s *settingsPanel) setErrors(errs []config.FieldError) {
+	s.errs = map[string]string{}
+	if s.readErr != nil {
+		errs = append(errs, config.FieldError{Path: "mtl-config.yml", Message: s.readErr.Error() + ", fix it before saving"})
+	}
+	for _, err := range errs {
+		log.Warningf("Invalid config: %v", err)
+		s.errs[err.Path] = err.Message
+	}
+}
+
+// layout is the settings panel widget.
+func (s *settingsPanel) layout(gtx C, th *material.Theme) D {
+	colorBox(gtx, gtx.Constraints.Max, DarkGray)
+
+	label := func(txt string) layout.Widget {
+		return func(gtx C) D {
+			return layout.Inset{Top: unit.Dp(12)}.Layout(gtx, func(gtx C) D {
+				l := material.Body1(th, txt)
+				l.Color = LightGray
+				return l.Layout(gtx)
+			})
+		}
+	}
+	errLabel := func(path string) layout.Widget {
+		return func(gtx C) D {
+			msg, ok := s.errs[path]
+			if !ok {
+				return D{}
+			}
+			l := material.Body2(th, msg)
+			l.Color = errorRed
+			return l.Layout(gtx)
+		}
+	}
+	field := func(editor *widget.Editor, hint string) layout.Widget {
+		return func(gtx C) D {
+			return widget.Border{Color: Gray, Width: unit.Dp(1)}.Layout(gtx, func(gtx C) D {
+				return layout.UniformInset(unit.Dp(6)).Layout(gtx, func(gtx C) D {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+					e := material.Editor(th, editor, hint)
+					e.Font = text.Font{Typeface: "Noto"}
+					e.Color = LightGray
+					e.HintColor = Gray
+					return e.Layout(gtx)
+				})
+			})
+		}
+	}
+	radio := func(key, txt string) layout.FlexChild {
+		return layout.Rigid(func(gtx C) D {
+			r := material.RadioButton(th, &s.service, key, txt)
+			r.Color = LightGray
+			r.IconColor = LightGray
+			return r.Layout(gtx)
+		})
+	}
+
+	rows := []layout.Widget{
+		func(gtx C) D {
+			l := material.H4(th, "Settings")
+			l.Color = LightGray
+			return l.Layout(gtx)
+		},
+		func(gtx C) D {
+			if s.inUse.Profile == "" && s.inUse.Series == "" {
+				return D{}
+			}
+			l := material.Body2(th, "Some of these fields are overridden, see the settings in use below.")
+			l.Color = Gray
+			return l.Layout(gtx)
+		},
+		// Errors which don't belong to any of the fields below, such as unknown fields.
+		func(gtx C) D {
+			var other []config.FieldError
+			for path, msg := range s.errs {
+				if !settingsFields[path] {
+					other = append(other, config.FieldError{Path: path, Message: msg})
+				}
+			}
+			if len(other) == 0 {
+				return D{}
+			}
+			sort.Slice(other, func(i, j int) bool { return other[i].Path < other[j].Path })
+			l := material.Body2(th, config.FormatErrors(other))
+			l.Color = errorRed
+			return l.Layout(gtx)
+		},
+		label("Vision API service account key (path)"),
+		field(&s.credentials, "/path/to/credentials.json"),
+		errLabel("cloudVision.credentialsPath"),
+		label("Translation service"),
+		func(gtx C) D {
+			return layout.Flex{}.Layout(gtx,
+				radio("google", "Google Cloud Translation"),
+				radio("deepL", "DeepL"),
+			)
+		},
+		errLabel("translation.selectedService"),
+		label("Source language (leave blank to detect automatically)"),
+		field(&s.source, "e.g. ja"),
+		errLabel("translation.sourceLanguage"),
+		label("Target language"),
+		field(&s.target, "e.g. en or EN-US"),
+		errLabel("translation.targetLanguage"),
+		label("Google Cloud Translation API key (leave blank to use the service account key)"),
+		field(&s.googleKey, "API key"),
+		errLabel("translation.google.apiKey"),
+		label("DeepL API key"),
+		field(&s.deepLKey, "API key"),
+		errLabel("translation.deepL.apiKey"),
+		func(gtx C) D {
+			return layout.Inset{Top: unit.Dp(16)}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						return layout.Inset{Right: unit.Dp(6)}.Layout(gtx, func(gtx C) D {
+							bt := material.Button(th, &s.saveBtn, "Save")
+							bt.Background = Gray
+							return bt.Layout(gtx)
+						})
+					}),
+					layout.Rigid(func(gtx C) D {
+						bt := material.Button(th, &s.cancelBtn, "Cancel")
+						bt.Background = Gray
+						return bt.Layout(gtx)
+					}),
+				)
+			})
+		},
+		label("Settings in use"),
+		func(gtx C) D {
+			l := material.Body2(th, s.inUseText())
+			l.Font = text.Font{Typeface: "Noto"}
+			l.Color = Gray
+			return l.Layout(gtx)
+		},
+	}
+
+	return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+		return s.list.Layout(gtx, len(rows), func(gtx C, i int) D {
+			return rows[i](gtx)
+		})
+	})
+}
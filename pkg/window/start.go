@@ -0,0 +1,94 @@
+package window
+
+import (
+	"fmt"
+	"gioui.org/app"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/cameronkinsella/manga-translator/pkg/history"
+	log "github.com/sirupsen/logrus"
+)
+
+// StartScreen shows the list of recently read chapters and returns the one selected by the user.
+// Returns nil if the window was closed before a chapter was selected.
+func StartScreen(w *app.Window, entries []history.Entry) (*history.Entry, error) {
+	var ops op.Ops
+	th := newTheme()
+
+	buttons := make([]widget.Clickable, len(entries))
+	list := layout.List{Axis: layout.Vertical}
+
+	for e := range w.Events() {
+		switch e := e.(type) {
+		case system.FrameEvent:
+			gtx := layout.NewContext(&ops, e)
+
+			for i := range buttons {
+				if buttons[i].Clicked() {
+					log.Infof("Re-opening chapter: %v", entries[i].Title)
+					// The next frame will be drawn by the chapter's window.
+					w.Invalidate()
+					return &entries[i], nil
+				}
+			}
+
+			colorBox(gtx, gtx.Constraints.Max, DarkGray)
+			layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+						l := material.H4(th, "Recently Read")
+						l.Font = text.Font{Typeface: "Noto"}
+						l.Color = LightGray
+						return l.Layout(gtx)
+					})
+				}),
+				layout.Rigid(divider),
+				layout.Flexed(1, func(gtx C) D {
+					if len(entries) == 0 {
+						return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+							l := material.Body1(th, "No history yet. Open images by passing their paths to manga-translator.")
+							l.Color = LightGray
+							return l.Layout(gtx)
+						})
+					}
+					return list.Layout(gtx, len(entries), func(gtx C, i int) D {
+						return historyItem(gtx, th, &buttons[i], entries[i])
+					})
+				}),
+			)
+			e.Frame(gtx.Ops)
+
+		case system.DestroyEvent:
+			return nil, e.Err
+		}
+	}
+	return nil, nil
+}
+
+// historyItem is a clickable row of the history list.
+func historyItem(gtx C, th *material.Theme, btn *widget.Clickable, entry history.Entry) D {
+	gtx.Constraints.Min.X = gtx.Constraints.Max.X
+	return Clickable(gtx, btn, true, func(gtx C) D {
+		return layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					l := material.H6(th, entry.Title)
+					l.Font = text.Font{Typeface: "Noto"}
+					l.Color = LightGray
+					return l.Layout(gtx)
+				}),
+				layout.Rigid(func(gtx C) D {
+					info := fmt.Sprintf("Page %d/%d · %s", entry.Page+1, entry.Pages, entry.LastRead.Format("2006-01-02 15:04"))
+					l := material.Body2(th, info)
+					l.Color = Gray
+					return l.Layout(gtx)
+				}),
+			)
+		})
+	})
+}
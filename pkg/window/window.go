@@ -1,10 +1,12 @@
 package window
 
 import (
+	"context"
 	"fmt"
 	"gioui.org/app"
 	"gioui.org/font/gofont"
 	"gioui.org/font/opentype"
+	"gioui.org/io/clipboard"
 	"gioui.org/io/key"
 	"gioui.org/io/system"
 	"gioui.org/layout"
@@ -17,11 +19,17 @@ import (
 	"gioui.org/widget/material"
 	"github.com/cameronkinsella/manga-translator/pkg/config"
 	"github.com/cameronkinsella/manga-translator/pkg/detect"
+	"github.com/cameronkinsella/manga-translator/pkg/history"
 	imageW "github.com/cameronkinsella/manga-translator/pkg/image"
+	"github.com/cameronkinsella/manga-translator/pkg/session"
 	"github.com/gonoto/notosans"
 	log "github.com/sirupsen/logrus"
 	"image"
 	"image/color"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 )
 
 type (
@@ -36,10 +44,18 @@ var (
 	LightGray = color.NRGBA{R: 0xCF, G: 0xCF, B: 0xCF, A: 0xFF}
 )
 
-var preLoadPages = 2 // hard-coded
+// Options are the display options given on the command line.
+type Options struct {
+	Spread     bool           // Show pages in pairs, in reading order.
+	Split      bool           // Split double-page spreads into two pages.
+	SplitRatio float32        // Width to height ratio above which an image is considered a spread.
+	Profile    string         // Config profile given with --profile, blank if none was given.
+	Series     *config.Series // Series file of the given images, nil if there is none.
+}
 
 // DrawFrame squares with labels, buttons control labels.
-func DrawFrame(w *app.Window, images []imageW.TranslatorImage, cfg config.File) error {
+// "chapter" is the history entry of the given images, its reading position is restored and saved when the window is closed.
+func DrawFrame(w *app.Window, images []imageW.TranslatorImage, cfg config.File, opts Options, chapter history.Entry) error {
 
 	// ops are the operations from the UI.
 	var ops op.Ops
@@ -47,14 +63,22 @@ func DrawFrame(w *app.Window, images []imageW.TranslatorImage, cfg config.File)
 	// split is the primary application widget containing the image, translation widget, and adjustment bar.
 	var split = VSplit{Ratio: 0.60}
 
+	// API clients are kept for the lifetime of the window, and the pages are loaded by a pool of workers.
+	sess := session.New()
+	ld := newLoader(sess, cfg.Preload.WorkerCount())
+
 	var p pageList
+	p.loader = ld
 	p.add(images)
+	p.spread = opts.Spread
+	p.rtl = cfg.RightToLeft()
+	resume(&p, &split, chapter)
 
 	log.Debugf("Number of pages loaded: %d", p.len)
 
-	// Start loading pages.
-	go p.pages[p.idx].load(w, &cfg)
-	p.preLoad(preLoadPages, w, &cfg)
+	// Start loading pages. In spread view, a saved page in the middle of a spread is moved to the start of it.
+	p.jump(p.idx, &cfg)
+	selectedPage := p.idx // Page of the selected text block.
 
 	// Button widgets which will be placed over the translation widget for copying text to clipboard.
 	var (
@@ -62,16 +86,60 @@ func DrawFrame(w *app.Window, images []imageW.TranslatorImage, cfg config.File)
 		translatedBtn = new(widget.Clickable)
 	)
 
-	// Create material theme with Noto font to support a wide range of unicode.
-	fonts := gofont.Collection()
-	fonts = appendOTC(fonts, text.Font{Typeface: "Noto"}, notosans.OTC())
-	th := material.NewTheme(fonts)
+	th := newTheme()
 
 	var (
 		selectedO string // Original text
 		selectedT string // Translated text
 	)
 
+	// Page navigation widgets.
+	var (
+		showThumbs bool
+		thumbList  = layout.List{Axis: layout.Horizontal}
+		gotoOpen   bool
+		gotoEditor = widget.Editor{SingleLine: true, Submit: true}
+		search     searchPanel
+		exporter   exportPrompt
+	)
+
+	// Images opened without restarting the application are loaded in the background and sent through this channel.
+	var (
+		browser     fileBrowser
+		opened      = make(chan openResult)
+		pasteAppend bool // Append the pasted images instead of replacing the current pages.
+		dropped     = make(chan droppedFiles)
+		dropping    bool // Files dropped onto the window are accepted, once the window was created.
+	)
+
+	var (
+		settings settingsPanel
+		profiles profilePicker
+		profile  = opts.Profile // Profile chosen with --profile or in the profile picker.
+		series   = opts.Series  // Series file of the open chapter.
+	)
+	setTitle(w, cfg)
+
+	// Changes to the config file are picked up while the application is running.
+	var (
+		reload        reloadPrompt
+		configChanged = make(chan struct{}, 1)
+	)
+	if err := config.Watch(func() {
+		select {
+		case configChanged <- struct{}{}:
+		default:
+		}
+	}); err != nil {
+		log.Warningf("Unable to watch the config file: %v", err)
+	}
+
+	// Show the problems with the config before anything else, since no pages can be translated until they are fixed.
+	if errs := config.Check(cfg); len(errs) > 0 {
+		settings.show(profile, series, cfg)
+		settings.setErrors(errs)
+	}
+
 	// Listen for events in the window.
 	for {
 		select {
@@ -80,24 +148,133 @@ func DrawFrame(w *app.Window, images []imageW.TranslatorImage, cfg config.File)
 
 			// This is sent when the application should re-render.
 			case system.FrameEvent:
+				if !dropping {
+					dropping = true
+					if err := acceptDrops(func(paths []string, appendPages bool) {
+						// Called by the window, which must not wait for the event loop.
+						go func() { dropped <- droppedFiles{paths, appendPages} }()
+					}); err != nil {
+						log.Debugf("Dropped files are not accepted: %v", err)
+					}
+				}
 				gtx := layout.NewContext(&ops, e)
 
+				if settings.open {
+					if _, saved := settings.update(); saved {
+						// Apply the new config to the running session. The profile, series file and environment
+						// variables still take precedence.
+						var newCfg config.File
+						if err := config.Setup(config.Path(), profile, series, &newCfg); err != nil {
+							log.Errorf("Unable to apply the saved config: %v", err)
+						} else {
+							cfg = newCfg
+							p.retry(&cfg)
+						}
+					}
+					settings.layout(gtx, th)
+					e.Frame(gtx.Ops)
+					break
+				}
+
+				if profiles.open {
+					if name, picked := profiles.update(); picked {
+						var newCfg config.File
+						if err := config.Setup(config.Path(), name, series, &newCfg); err != nil {
+							log.Errorf("Unable to switch profile: %v", err)
+						} else {
+							log.Infof("Switching to profile: %q", name)
+							cfg, profile = newCfg, name
+							setTitle(w, cfg)
+							// Translate the pages again with the new profile, the detected text is reused from cache.
+							p.reload(&cfg)
+							selectedO, selectedT, selectedPage = "", "", p.idx
+						}
+					}
+					profiles.layout(gtx, th, cfg.Profile)
+					e.Frame(gtx.Ops)
+					break
+				}
+
+				if browser.open {
+					if paths, appendPages := browser.update(); len(paths) > 0 {
+						go openImages(paths, appendPages, opts, cfg, opened)
+					}
+					browser.layout(gtx, th)
+					e.Frame(gtx.Ops)
+					break
+				}
+
+				if search.open {
+					if hit, picked := search.update(p); picked {
+						log.Debugf("Jumping to search result on page %d", hit.page)
+						p.jump(hit.page, &cfg)
+						thumbList.Position.First = hit.page
+						selectedO, selectedT, selectedPage = hit.block.Text, hit.block.Translated, hit.page
+						// Give the keyboard back to the window.
+						key.FocusOp{}.Add(gtx.Ops)
+						w.Invalidate()
+					}
+					if search.open {
+						search.layout(gtx, th, p)
+						e.Frame(gtx.Ops)
+						break
+					}
+				}
+
+				if exporter.open {
+					exporter.update(p, chapter.Paths)
+				}
+
+				if reload.update() {
+					log.Info("Translating loaded pages again with the new config")
+					p.reload(&cfg)
+					selectedO, selectedT, selectedPage = "", "", p.idx
+				}
+
 				// Handle when any of the blocks are clicked.
-				for i, b := range p.pages[p.idx].blocks {
-					if p.pages[p.idx].blockButtons[i].Clicked() {
-						log.Debugf("Clicked Block %d", i)
-						selectedO = b.Text
-						selectedT = b.Translated
+				for _, pg := range p.visible() {
+					for i, b := range p.pages[pg].blocks {
+						if p.pages[pg].blockButtons[i].Clicked() {
+							log.Debugf("Clicked Block %d on page %d", i, pg)
+							selectedO = b.Text
+							selectedT = b.Translated
+							selectedPage = pg
+						}
+					}
+				}
+
+				// Jump to a page if its thumbnail was clicked.
+				for i := range p.pages {
+					if p.pages[i].thumbButton.Clicked() {
+						log.Debugf("Clicked thumbnail %d", i)
+						p.jump(i, &cfg)
+						selectedO, selectedT, selectedPage = "", "", p.idx
+					}
+				}
+
+				// Jump to the page number entered in the "go to page" box.
+				for _, ev := range gotoEditor.Events() {
+					if ev, ok := ev.(widget.SubmitEvent); ok {
+						if n, err := strconv.Atoi(strings.TrimSpace(ev.Text)); err == nil && n >= 1 && n <= p.len {
+							p.jump(n-1, &cfg)
+							thumbList.Position.First = n - 1
+							selectedO, selectedT, selectedPage = "", "", p.idx
+						}
+						gotoOpen = false
+						gotoEditor.SetText("")
+						// Give the keyboard back to the window.
+						key.FocusOp{}.Add(gtx.Ops)
 					}
 				}
+				txt := p.pages[selectedPage].text
 
 				// Write to clipboard if either of the text sections are clicked.
 				if originalBtn.Clicked() {
 					// Since originalBtn is reused for the loading screen,
 					// we need to check if we are writing the correct text to the clipboard.
-					if !p.pages[p.idx].text.finished || !p.pages[p.idx].text.ok {
+					if !txt.finished || !txt.ok {
 						// Loading or error status.
-						w.WriteClipboard(p.pages[p.idx].text.status)
+						w.WriteClipboard(txt.status)
 					} else {
 						// Original text. Detection and translation completed and succeeded.
 						w.WriteClipboard(selectedO)
@@ -113,44 +290,302 @@ func DrawFrame(w *app.Window, images []imageW.TranslatorImage, cfg config.File)
 
 				// Application
 				split.Layout(gtx, func(gtx C) D {
-					return imageWidget(gtx, th, p)
+					var d D
+					if showThumbs {
+						d = layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Flexed(1, func(gtx C) D {
+								return imageWidget(gtx, th, p)
+							}),
+							layout.Rigid(func(gtx C) D {
+								return thumbnailStrip(gtx, th, p, &thumbList)
+							}),
+						)
+					} else {
+						d = imageWidget(gtx, th, p)
+					}
+					if gotoOpen {
+						gotoWidget(gtx, th, &gotoEditor, p.len)
+					}
+					if reload.open {
+						reload.layout(gtx, th)
+					} else if exporter.open {
+						exporter.layout(gtx, th)
+					}
+					return d
 				}, func(gtx C) D {
-					return translatorPanelWidget(gtx, th, p.pages[p.idx].text, originalBtn, translatedBtn, p.pages[p.idx].text.status, selectedO, selectedT)
+					return translatorPanelWidget(gtx, th, txt, originalBtn, translatedBtn, txt.status, selectedO, selectedT)
 				})
 				e.Frame(gtx.Ops)
 
 			// This is sent when a key is pressed.
 			case key.Event:
+				if browser.open || settings.open || profiles.open || search.open {
+					if e.State == key.Press && e.Name == key.NameEscape {
+						browser.open, settings.open, profiles.open, search.open = false, false, false, false
+						w.Invalidate()
+					}
+					break
+				}
 				if e.State == key.Press {
-					if (e.Name == "→" || e.Name == "D") && p.idx < p.len-1 {
-						p.idx++
-						selectedO, selectedT = "", ""
-						p.preLoad(preLoadPages, w, &cfg)
+					if e.Name == "V" && e.Modifiers.Contain(key.ModShortcut) {
+						// Open the images which were copied to the clipboard.
+						pasteAppend = e.Modifiers.Contain(key.ModShift)
+						w.ReadClipboard()
+					} else if e.Name == "," {
+						settings.show(profile, series, cfg)
+						w.Invalidate()
+					} else if e.Name == "P" {
+						profiles.show()
+						w.Invalidate()
+					} else if e.Name == "O" {
+						dir := ""
+						if len(chapter.Paths) > 0 && !chapter.URL {
+							dir = filepath.Dir(chapter.Paths[0])
+						}
+						browser.show(dir)
+						w.Invalidate()
+					} else if (e.Name == "→" || e.Name == "D") && p.idx+p.step() < p.len {
+						p.idx += p.step()
+						selectedO, selectedT, selectedPage = "", "", p.idx
+						p.preLoad(&cfg)
 						w.Invalidate()
 					} else if (e.Name == "←" || e.Name == "A") && p.idx > 0 {
-						p.idx--
-						selectedO, selectedT = "", ""
+						p.idx -= p.step()
+						if p.idx < 0 {
+							p.idx = 0
+						}
+						selectedO, selectedT, selectedPage = "", "", p.idx
+						p.preLoad(&cfg)
+						w.Invalidate()
+					} else if e.Name == "T" {
+						showThumbs = !showThumbs
+						w.Invalidate()
+					} else if e.Name == "E" {
+						exporter.open, exporter.status = true, ""
+						w.Invalidate()
+					} else if e.Name == "F" {
+						search.show()
+						w.Invalidate()
+					} else if e.Name == "G" {
+						gotoOpen = true
+						gotoEditor.Focus()
+						w.Invalidate()
+					} else if e.Name == "S" {
+						// Toggle between single page and spread view.
+						p.spread = !p.spread
+						log.Debugf("Spread view: %v", p.spread)
+						p.jump(p.idx, &cfg)
+						selectedO, selectedT, selectedPage = "", "", p.idx
 						w.Invalidate()
 					}
 				}
+			// This is sent when the clipboard is read.
+			case clipboard.Event:
+				if paths := parsePaths(e.Text); len(paths) > 0 {
+					log.Infof("Opening pasted images: %v", paths)
+					go openImages(paths, pasteAppend, opts, cfg, opened)
+				}
+
 			// This is sent when the application window is closed.
 			case system.DestroyEvent:
+				savePosition(&chapter, p, split)
+				ld.close()
+				if err := sess.Close(); err != nil {
+					log.Errorf("Unable to close API clients: %v", err)
+				}
 				return e.Err
 			}
+
+		// This is sent when a worker made progress on a page or finished it.
+		case r := <-ld.results:
+			r.page.apply(r)
+			w.Invalidate()
+
+		// This is sent when the config file was changed outside the application.
+		case <-configChanged:
+			var newCfg config.File
+			if err := config.Setup(config.Path(), profile, series, &newCfg); err != nil {
+				// E.g. half-saved by an editor. The config in use is kept until the file can be read again.
+				log.Errorf("Unable to reload the config: %v", err)
+				reload.open, reload.err = true, err
+				w.Invalidate()
+				break
+			}
+			if reflect.DeepEqual(newCfg, cfg) {
+				// E.g. saved from the settings panel, which already applied it, or the problem was undone.
+				if reload.err != nil {
+					reload.open, reload.err = false, nil
+					w.Invalidate()
+				}
+				break
+			}
+			log.Info("Config file changed, reloading")
+			reload.open, reload.err = false, nil
+			cfg = newCfg
+			p.rtl = cfg.RightToLeft()
+			setTitle(w, cfg)
+			// Pages which were not translated yet use the new config, failed pages are retried with it.
+			p.retry(&cfg)
+			if n := p.translated(); n > 0 {
+				reload.open = true
+				reload.pages = n
+			}
+			w.Invalidate()
+
+		// This is sent when the cache was searched from the search panel.
+		case r := <-search.results:
+			search.searched(r, p)
+			w.Invalidate()
+
+		// This is sent when files are dropped onto the window.
+		case d := <-dropped:
+			if paths := parsePaths(strings.Join(d.paths, "\n")); len(paths) > 0 {
+				log.Infof("Opening dropped images: %v", paths)
+				go openImages(paths, d.appendPages, opts, cfg, opened)
+			}
+
+		// This is sent when images opened in the application are ready.
+		case r := <-opened:
+			if r.err != nil {
+				log.Errorf("Failed to open images: %v", r.err)
+				browser.open = true
+				browser.status = r.err.Error()
+				w.Invalidate()
+				break
+			}
+			browser.open = false
+
+			if !r.appendPages && r.series != series {
+				// The new chapter belongs to a different series, or has no series file.
+				var newCfg config.File
+				if err := config.Setup(config.Path(), profile, r.series, &newCfg); err != nil {
+					log.Errorf("Unable to apply the series file: %v", err)
+				} else {
+					cfg, series = newCfg, r.series
+					setTitle(w, cfg)
+				}
+			}
+
+			if r.appendPages {
+				log.Infof("Appending %d pages", len(r.images))
+				p.add(r.images)
+				chapter.Append(r.paths, r.hashes, isURL(r.paths[0]))
+			} else {
+				log.Infof("Replacing pages with %d new pages", len(r.images))
+				// Remember where we were in the previous chapter before replacing it.
+				savePosition(&chapter, p, split)
+				chapter = history.Lookup(r.paths, r.hashes, isURL(r.paths[0]), false)
+				// Stop loading the pages of the previous chapter.
+				for _, pg := range p.pages {
+					pg.reset()
+				}
+				p = pageList{loader: ld, spread: p.spread, rtl: cfg.RightToLeft()}
+				p.add(r.images)
+				resume(&p, &split, chapter)
+				selectedO, selectedT = "", ""
+			}
+			selectedPage = p.idx
+			p.jump(p.idx, &cfg)
+			w.Invalidate()
+		}
+	}
+}
+
+// setTitle shows the profile and series of the given config in the window title.
+func setTitle(w *app.Window, cfg config.File) {
+	title := "Manga Translator"
+	if cfg.Profile != "" {
+		title += " - " + cfg.Profile
+	}
+	if cfg.Series != "" {
+		title += " - " + filepath.Base(filepath.Dir(cfg.Series))
+	}
+	w.Option(app.Title(title))
+}
+
+// resume moves the given pageList and split to the reading position saved in the given history entry.
+func resume(p *pageList, split *VSplit, chapter history.Entry) {
+	if chapter.Page > 0 && chapter.Page < p.len {
+		log.Infof("Resuming from page %d", chapter.Page+1)
+		p.idx = chapter.Page
+	}
+	if chapter.Ratio != 0 {
+		split.Ratio = chapter.Ratio
+	}
+}
+
+// savePosition saves the reading position of the given pageList and split in the history.
+func savePosition(chapter *history.Entry, p pageList, split VSplit) {
+	chapter.Page = p.idx
+	chapter.Pages = p.len
+	chapter.Ratio = split.Ratio
+	history.Save(*chapter)
+}
+
+// openResult is the result of opening images without restarting the application.
+type openResult struct {
+	images      []imageW.TranslatorImage
+	paths       []string
+	hashes      []string       // Hashes of the opened files, before spreads are split.
+	series      *config.Series // Series file of the opened images.
+	appendPages bool
+	err         error
+}
+
+// droppedFiles are the paths of the files dropped onto the window.
+type droppedFiles struct {
+	paths       []string
+	appendPages bool // Shift was held, append the images instead of replacing the current pages.
+}
+
+// openImages opens the images at the given paths/URLs and sends them to the given channel.
+// "cfg" is the config in use, its reading direction is used unless the images have their own series file.
+func openImages(paths []string, appendPages bool, opts Options, cfg config.File, results chan<- openResult) {
+	r := openResult{paths: paths, appendPages: appendPages}
+	rightToLeft := cfg.RightToLeft()
+	if !appendPages {
+		series, err := config.FindSeries(ChapterDir(paths))
+		if err != nil {
+			r.err = err
+			results <- r
+			return
+		}
+		r.series = series
+		if series != nil {
+			rightToLeft = series.ReadingDirection != "ltr"
 		}
 	}
+	for _, path := range paths {
+		log.Debugf("Getting image info for: %v", path)
+		img, err := imageW.Load(path, isURL(path), false)
+		if err != nil {
+			r.err = fmt.Errorf("%s: %w", filepath.Base(path), err)
+			break
+		}
+		r.hashes = append(r.hashes, img.Hash)
+		if opts.Split && img.IsSpread(opts.SplitRatio) {
+			r.images = append(r.images, img.SplitSpread(rightToLeft)...)
+			continue
+		}
+		r.images = append(r.images, img)
+	}
+	results <- r
 }
 
 type pageList struct {
-	pages []page
-	idx   int // Current page.
-	len   int
+	pages  []*page
+	idx    int // Current page. In spread view, this is the first page in reading order.
+	len    int
+	spread bool // Show pages in pairs, in reading order.
+	rtl    bool // Pages are read from right to left.
+
+	loader *loader // Loader used to detect and translate the text of the pages.
 }
 
 // add inserts the given slice of TranslatorImages into the pageList.
 func (p *pageList) add(images []imageW.TranslatorImage) {
 	for _, img := range images {
-		newPage := page{
+		newPage := &page{
 			image: img,
 		}
 		p.pages = append(p.pages, newPage)
@@ -158,56 +593,180 @@ func (p *pageList) add(images []imageW.TranslatorImage) {
 	}
 }
 
-// preLoad loads the given number of pages after the current page.
-func (p *pageList) preLoad(num int, w *app.Window, cfg *config.File) {
-	for i := 1; i <= num && i+p.idx < p.len; i++ {
-		// Asynchronously detect and translate text.
-		go p.pages[i+p.idx].load(w, cfg)
+// step returns the number of pages shown at once.
+func (p *pageList) step() int {
+	if p.spread {
+		return 2
+	}
+	return 1
+}
+
+// visible returns the indexes of the pages currently shown, in reading order.
+func (p *pageList) visible() []int {
+	if p.spread && p.idx+1 < p.len {
+		return []int{p.idx, p.idx + 1}
+	}
+	return []int{p.idx}
+}
+
+// jump moves to the given page and loads the pages around it. In spread view, it moves to the spread which contains
+// the page, so the pages are always paired the same way.
+func (p *pageList) jump(idx int, cfg *config.File) {
+	if p.spread {
+		idx -= idx % 2
+	}
+	log.Debugf("Jumping to page %d", idx)
+	p.idx = idx
+	p.preLoad(cfg)
+}
+
+// retry reloads the pages which failed to load, and loads the pages around the current page.
+func (p *pageList) retry(cfg *config.File) {
+	for _, pg := range p.pages {
+		if pg.text.finished && !pg.text.ok {
+			pg.reset()
+		}
+	}
+	p.jump(p.idx, cfg)
+}
+
+// reload clears the text blocks of all pages, and loads the pages around the current page again.
+func (p *pageList) reload(cfg *config.File) {
+	for _, pg := range p.pages {
+		pg.reset()
+	}
+	p.jump(p.idx, cfg)
+}
+
+// translated returns the number of pages which were translated successfully.
+func (p *pageList) translated() int {
+	n := 0
+	for _, pg := range p.pages {
+		if pg.text.finished && pg.text.ok {
+			n++
+		}
+	}
+	return n
+}
+
+// preLoad loads the visible pages and the pages around them, as configured by the preload section of the config.
+// The pages nearest to the current page are loaded first. If enabled in the config, the pages which are still
+// loading but are no longer near the current page are cancelled.
+func (p *pageList) preLoad(cfg *config.File) {
+	first, last := p.idx-cfg.Preload.BehindCount()*p.step(), p.idx+p.step()-1+cfg.Preload.AheadCount()*p.step()
+	if cfg.Preload.Chapter {
+		first, last = 0, p.len-1
+	}
+	if first < 0 {
+		first = 0
+	}
+	if last >= p.len {
+		last = p.len - 1
+	}
+
+	p.loader.setCurrent(p.idx)
+	for i := first; i <= last; i++ {
+		p.load(i, cfg)
+	}
+	if cfg.Preload.CancelOutside {
+		p.cancelOutside(first, last)
+	}
+}
+
+// cancelOutside cancels the loading pages which are not between the given pages (inclusive).
+func (p *pageList) cancelOutside(first, last int) {
+	for i, pg := range p.pages {
+		if (i < first || i > last) && (pg.text.queued || pg.text.loading) {
+			log.Debugf("Cancelling page %d", i)
+			pg.cancel()
+		}
+	}
+}
+
+// load queues the given page to have its text detected and translated with the given config,
+// unless it is already loading or finished.
+func (p *pageList) load(idx int, cfg *config.File) {
+	pg := p.pages[idx]
+	if pg.text.queued || pg.text.loading || pg.text.finished {
+		return
 	}
+	ctx, cancel := context.WithCancel(p.loader.sess.Context())
+	pg.cancel = cancel
+	pg.text = textBlocks{queued: true, status: "Waiting..."}
+	p.loader.add(loadJob{page: pg, index: idx, gen: pg.gen, ctx: ctx, cfg: *cfg, img: pg.image})
 }
 
 // page is a pageList node which includes all necessary info to display an image and its translation.
+// Pages are only changed by the UI goroutine, the loader sends its results to it (see apply).
 type page struct {
 	image        imageW.TranslatorImage
 	blocks       []detect.TextBlock
 	blockButtons []widget.Clickable // Button widgets which will be placed over the text blocks.
 	text         textBlocks
+	thumb        paint.ImageOp      // Thumbnail shown in the thumbnail strip.
+	thumbButton  widget.Clickable   // Button widget for jumping to the page from its thumbnail.
+	cancel       context.CancelFunc // Cancels the requests of the page while it is loading.
+	gen          int                // Increased when the page is reset, so results of earlier loads are ignored.
 }
 
-// load fetches the text annotations and translations for page.
-func (p *page) load(w *app.Window, cfg *config.File) {
-	// Only fetch if page is not already loading or finished.
-	if !p.text.loading && !p.text.finished {
-		// Detect and translate text.
-		p.text.getText(w, cfg, p.image, &p.blocks, &p.blockButtons)
+// apply updates the page with the given result from the loader.
+func (pg *page) apply(r loadResult) {
+	if r.gen != pg.gen {
+		// The page was reset after the job was queued.
+		return
+	}
+	switch {
+	case r.cancelled:
+		pg.reset()
+	case r.done:
+		pg.cancel()
+		pg.blocks = r.blocks
+		pg.blockButtons = make([]widget.Clickable, len(r.blocks))
+		pg.text = textBlocks{status: r.status, finished: true, ok: r.status == statusOK}
+	default:
+		pg.text.queued, pg.text.loading = false, true
+		pg.text.status = r.status
 	}
 }
 
+// reset clears the text blocks of the page and cancels its requests, so they will be fetched again the next time
+// the page is loaded.
+func (pg *page) reset() {
+	if pg.cancel != nil {
+		pg.cancel()
+	}
+	pg.gen++
+	pg.blocks = nil
+	pg.blockButtons = nil
+	pg.text = textBlocks{}
+}
+
 // imageWidget is the main image and text boxes.
 func imageWidget(gtx C, th *material.Theme, p pageList) D {
-	mainImg := layout.Center.Layout(gtx, func(gtx C) D {
-		imgWidget := widget.Image{
-			Fit:      widget.Contain,
-			Position: layout.Center,
-			Src:      paint.NewImageOp(p.pages[p.idx].image.Image),
-		}.Layout(gtx)
-
-		// Add text blocks on top of the image widget.
-		var blockWidgets []layout.StackChild
-
-		if p.pages[p.idx].text.finished {
-			for i, block := range p.pages[p.idx].blocks {
-				blockWidgets = append(blockWidgets, blockBox(imgWidget, p.pages[p.idx].image.Dimensions, block, &p.pages[p.idx].blockButtons[i]))
-			}
+	visible := p.visible()
+	var mainImg D
+	if len(visible) == 1 {
+		mainImg = pageWidget(gtx, p.pages[p.idx], layout.Center)
+	} else {
+		// In right-to-left order, the first page is on the right.
+		left, right := visible[0], visible[1]
+		if p.rtl {
+			left, right = right, left
 		}
-
-		layout.Stack{}.Layout(gtx, blockWidgets...)
-
-		return imgWidget
-	},
-	)
+		mainImg = layout.Flex{}.Layout(gtx,
+			layout.Flexed(0.5, func(gtx C) D {
+				return pageWidget(gtx, p.pages[left], layout.E)
+			}),
+			layout.Flexed(0.5, func(gtx C) D {
+				return pageWidget(gtx, p.pages[right], layout.W)
+			}),
+		)
+	}
 	if p.len > 1 {
 		pageNum := fmt.Sprintf("%d/%d", p.idx+1, p.len)
+		if len(visible) > 1 {
+			pageNum = fmt.Sprintf("%d-%d/%d", visible[0]+1, visible[1]+1, p.len)
+		}
 		return layout.NW.Layout(gtx, func(gtx C) D {
 			return layout.Inset{
 				Left: unit.Dp(4),
@@ -223,6 +782,30 @@ func imageWidget(gtx C, th *material.Theme, p pageList) D {
 	}
 }
 
+// pageWidget is the image of a single page with its text boxes, aligned with the given direction.
+func pageWidget(gtx C, pg *page, direction layout.Direction) D {
+	return direction.Layout(gtx, func(gtx C) D {
+		imgWidget := widget.Image{
+			Fit:      widget.Contain,
+			Position: direction,
+			Src:      paint.NewImageOp(pg.image.Image),
+		}.Layout(gtx)
+
+		// Add text blocks on top of the image widget.
+		var blockWidgets []layout.StackChild
+
+		if pg.text.finished {
+			for i, block := range pg.blocks {
+				blockWidgets = append(blockWidgets, blockBox(imgWidget, pg.image.Dimensions, block, &pg.blockButtons[i]))
+			}
+		}
+
+		layout.Stack{}.Layout(gtx, blockWidgets...)
+
+		return imgWidget
+	})
+}
+
 // translatorPanelWidget is the full translation panel containing either the original text and translation or the current status.
 func translatorPanelWidget(gtx C, th *material.Theme, txt textBlocks, originalBtn, translatedBtn *widget.Clickable, status, selectedO, selectedT string) D {
 	if !txt.finished {
@@ -249,6 +832,13 @@ func colorBox(gtx C, size image.Point, color color.NRGBA) D {
 	return D{Size: size}
 }
 
+// newTheme creates a material theme with Noto font to support a wide range of unicode.
+func newTheme() *material.Theme {
+	fonts := gofont.Collection()
+	fonts = appendOTC(fonts, text.Font{Typeface: "Noto"}, notosans.OTC())
+	return material.NewTheme(fonts)
+}
+
 // appendOTC adds the given OpenType font to the given font collection
 func appendOTC(collection []text.FontFace, fnt text.Font, otc []byte) []text.FontFace {
 	face, err := opentype.ParseCollection(otc)